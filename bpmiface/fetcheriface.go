@@ -9,6 +9,7 @@ import (
 
 type Fetcher interface {
 	Fetch(ctx context.Context, source string, version *bundle.VersionSpec) (*fetch.FetchOutput, error)
+	FetchAll(ctx context.Context, requests []*fetch.FetchRequest) ([]*bundle.Bundle, error)
 	PlainFetch(ctx context.Context, source string, version *bundle.VersionSpec) (*bundle.Bundle, error)
 	FetchLocal(ctx context.Context, source string, version *bundle.VersionSpec) (*bundle.Bundle, error)
 	FetchRemote(ctx context.Context, source string, version *bundle.VersionSpec) (*bundle.Bundle, error)
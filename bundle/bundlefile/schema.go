@@ -1,14 +1,15 @@
 package bundlefile
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 
 	"github.com/4rchr4y/bpm/bundleutil"
 	"github.com/4rchr4y/bpm/constant"
-	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/samber/lo"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type AuthorExpr struct {
@@ -79,16 +80,61 @@ func PrepareSchema(existing *Schema) *Schema {
 
 func (*Schema) Filename() string { return constant.BundleFileName }
 
+// Sum computes the checksum of the bundle file contents, regardless of
+// their formatting, since the schema is encoded in the canonical layout
 func (s *Schema) Sum() string {
-	f := hclwrite.NewEmptyFile()
-	gohcl.EncodeIntoBody(s, f.Body())
-
 	return bundleutil.ChecksumSHA256(
 		sha256.New(),
-		bundleutil.FormatBundleFile(f.Bytes()),
+		bytes.TrimSpace(s.Bytes()),
 	)
 }
 
+// Bytes encodes the schema in the canonical layout of the bundle file
+func (s *Schema) Bytes() []byte {
+	b := new(bundleutil.HCLBuilder)
+	separate := func() {
+		if !b.Empty() {
+			b.Newline()
+		}
+	}
+
+	if s.Package != nil {
+		separate()
+		b.Block("package", nil, func(b *bundleutil.HCLBuilder) {
+			b.Attribute("name", cty.StringVal(s.Package.Name))
+			b.Attribute("author", bundleutil.StringList(s.Package.Author))
+			b.Attribute("repository", cty.StringVal(s.Package.Repository))
+			b.Attribute("description", cty.StringVal(s.Package.Description))
+		})
+	}
+
+	if s.Workspace != nil {
+		separate()
+		b.Block("workspace", nil, func(b *bundleutil.HCLBuilder) {
+			b.Attribute("internal", bundleutil.StringList(s.Workspace.Internal))
+			b.Attribute("builtin", bundleutil.StringList(s.Workspace.Builtin))
+		})
+	}
+
+	if s.Require != nil {
+		separate()
+		b.Block("require", nil, func(b *bundleutil.HCLBuilder) {
+			for _, r := range s.Require.List {
+				if r == nil {
+					continue
+				}
+
+				b.Block("bundle", []hclwrite.Tokens{bundleutil.StringLabel(r.Source)}, func(b *bundleutil.HCLBuilder) {
+					b.Attribute("name", cty.StringVal(r.Name))
+					b.Attribute("version", cty.StringVal(r.Version))
+				})
+			}
+		})
+	}
+
+	return b.Bytes()
+}
+
 type FilterFn func(r *RequirementDecl) bool
 
 func FilterByVersion(version string) FilterFn {
@@ -3,9 +3,14 @@ package lockfile
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/4rchr4y/bpm/bundleutil"
 	"github.com/4rchr4y/bpm/constant"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/samber/lo"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type (
@@ -50,6 +55,23 @@ func NewModRequireSpec(line int, source, module string) ModRequireSpec {
 	}
 }
 
+// ParseModRequireSpec parses the spec in the form of 'line:source:module',
+// the source is allowed to contain colons, e.g. if it is an URL
+func ParseModRequireSpec(str string) (ModRequireSpec, error) {
+	lineStr, rest, ok1 := strings.Cut(str, ":")
+	idx := strings.LastIndex(rest, ":")
+	if !ok1 || idx == -1 {
+		return ModRequireSpec{}, fmt.Errorf("invalid module requirement '%s', expected 'line:source:module'", str)
+	}
+
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return ModRequireSpec{}, fmt.Errorf("invalid line of module requirement '%s': %v", str, err)
+	}
+
+	return NewModRequireSpec(line, rest[:idx], rest[idx+1:]), nil
+}
+
 type (
 	ModuleDecl struct {
 		Package    string   `hcl:"package,label"`    // rego file package name, 		e.g. 'data.example'
@@ -124,6 +146,65 @@ func PrepareSchema(existing *Schema) *Schema {
 
 func (*Schema) Filename() string { return constant.LockFileName }
 
+// Bytes encodes the schema in the canonical layout of the lock file.
+// Direction and visibility labels are written without quotes, and module
+// requirements are written one per line.
+func (s *Schema) Bytes() []byte {
+	b := new(bundleutil.HCLBuilder)
+	b.Attribute("sum", cty.StringVal(s.Sum))
+	b.Attribute("edition", cty.StringVal(s.Edition))
+
+	if s.Consist != nil {
+		b.Newline()
+		b.Block("consist", nil, func(b *bundleutil.HCLBuilder) {
+			for _, m := range s.Consist.List {
+				if m == nil {
+					continue
+				}
+
+				labels := []hclwrite.Tokens{
+					bundleutil.StringLabel(m.Package),
+					bundleutil.KeywordLabel(m.Visibility),
+				}
+
+				b.Block("module", labels, func(b *bundleutil.HCLBuilder) {
+					b.Attribute("source", cty.StringVal(m.Source))
+					b.Attribute("sum", cty.StringVal(m.Sum))
+
+					if len(m.Require) > 0 {
+						b.ListAttribute("require", m.Require)
+					}
+				})
+			}
+		})
+	}
+
+	if s.Require != nil {
+		b.Newline()
+		b.Block("require", nil, func(b *bundleutil.HCLBuilder) {
+			for _, r := range s.Require.List {
+				if r == nil {
+					continue
+				}
+
+				labels := []hclwrite.Tokens{
+					bundleutil.StringLabel(r.Source),
+					bundleutil.KeywordLabel(r.Direction),
+				}
+
+				b.Block("bundle", labels, func(b *bundleutil.HCLBuilder) {
+					b.Attribute("name", cty.StringVal(r.Name))
+					b.Attribute("version", cty.StringVal(r.Version))
+					b.Attribute("h1", cty.StringVal(r.H1))
+					b.Attribute("h2", cty.StringVal(r.H2))
+				})
+			}
+		})
+	}
+
+	return b.Bytes()
+}
+
 type RequireFilterFn func(r *RequirementDecl) bool
 type ModulesFilterFn func(r *ModuleDecl) bool
 
@@ -145,6 +226,12 @@ func RequireFilterBySource(source string) RequireFilterFn {
 	}
 }
 
+func RequireFilterByDirection(direction DirectionType) RequireFilterFn {
+	return func(r *RequirementDecl) bool {
+		return r.Direction == direction.String()
+	}
+}
+
 func (bf *Schema) SomeRequirement(filters ...RequireFilterFn) bool {
 	if bf.Require == nil {
 		return false
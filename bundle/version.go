@@ -25,9 +25,28 @@ var (
 )
 
 type VersionSpec struct {
-	SemTag    *version.Version // semantic tag if available, or pseudo semantic tag
-	Timestamp time.Time        // commit timestamp
-	Hash      string           // commit hash
+	SemTag     *version.Version   // semantic tag if available, or pseudo semantic tag
+	Timestamp  time.Time          // commit timestamp
+	Hash       string             // commit hash
+	Constraint *VersionConstraint // version constraint expression, e.g. '~> 1.2'
+}
+
+// VersionConstraint describes a range of acceptable versions
+// instead of a single concrete version.
+type VersionConstraint struct {
+	Expr        string              // original expression, e.g. '>= 1.0, < 2.0'
+	Constraints version.Constraints // parsed constraints
+}
+
+// Check reports whether the given semantic version satisfies the constraint.
+func (c *VersionConstraint) Check(v *version.Version) bool {
+	return v != nil && c.Constraints.Check(v)
+}
+
+// Equal reports whether both constraints accept the same versions,
+// regardless of the formatting and the order of their expressions.
+func (c *VersionConstraint) Equal(o *VersionConstraint) bool {
+	return c != nil && o != nil && c.Constraints.Equals(o.Constraints)
 }
 
 func NewVersionSpecFromCommit(commit *object.Commit, tag *version.Version) *VersionSpec {
@@ -38,6 +57,26 @@ func NewVersionSpecFromCommit(commit *object.Commit, tag *version.Version) *Vers
 	}
 }
 
+// IsConstraint reports whether the version is a constraint expression
+// that must be resolved into a concrete version before use.
+func (v *VersionSpec) IsConstraint() bool {
+	return v != nil && v.Constraint != nil
+}
+
+// Match reports whether the concrete version o is acceptable for v.
+// If v is a constraint, o must satisfy it, otherwise both versions must be equal.
+func (v *VersionSpec) Match(o *VersionSpec) bool {
+	if v == nil || o == nil || o.IsConstraint() {
+		return false
+	}
+
+	if v.IsConstraint() {
+		return !o.IsPseudo() && v.Constraint.Check(o.SemTag)
+	}
+
+	return v.Equal(o)
+}
+
 func (v *VersionSpec) IsPseudo() bool {
 	return v.SemTag != nil &&
 		v.SemTag.Original() == PseudoSemTagStr &&
@@ -70,6 +109,10 @@ func (v *VersionSpec) String() string {
 		return versionLatestStr
 	}
 
+	if v.Constraint != nil {
+		return v.Constraint.Expr
+	}
+
 	if v.SemTag != nil && v.SemTag.Original() != PseudoSemTagStr {
 		return v.SemTag.Original()
 	}
@@ -83,9 +126,17 @@ func (v *VersionSpec) String() string {
 
 func ParseVersionExpr(versionStr string) (*VersionSpec, error) {
 	switch {
-	case versionStr == "":
+	case versionStr == "" || versionStr == versionLatestStr:
 		return nil, nil
 
+	case isConstraintExpr(versionStr):
+		c, err := ParseVersionConstraint(versionStr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &VersionSpec{Constraint: c}, nil
+
 	case !strings.Contains(versionStr, "+"):
 		v, err := version.NewVersion(versionStr)
 		if err != nil {
@@ -117,3 +168,62 @@ func ParseVersionExpr(versionStr string) (*VersionSpec, error) {
 		}, nil
 	}
 }
+
+// ParseVersionConstraint parses a comma-separated list of constraints,
+// e.g. '~> 1.2', '>= 1.0, < 2.0' or '^1.4'. Besides the operators supported
+// by hashicorp/go-version, the caret operator is accepted: it allows changes
+// that do not modify the left-most non-zero segment of the version.
+func ParseVersionConstraint(expr string) (*VersionConstraint, error) {
+	parts := strings.Split(expr, ",")
+	normalized := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "^") {
+			normalized = append(normalized, part)
+			continue
+		}
+
+		caret, err := expandCaretConstraint(strings.TrimPrefix(part, "^"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint '%s': %v", part, err)
+		}
+
+		normalized = append(normalized, caret...)
+	}
+
+	constraints, err := version.NewConstraint(strings.Join(normalized, ", "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint '%s': %v", expr, err)
+	}
+
+	return &VersionConstraint{
+		Expr:        strings.TrimSpace(expr),
+		Constraints: constraints,
+	}, nil
+}
+
+// expandCaretConstraint converts '^1.4' into '>= 1.4, < 2.0.0'
+func expandCaretConstraint(versionStr string) ([]string, error) {
+	v, err := version.NewVersion(strings.TrimSpace(versionStr))
+	if err != nil {
+		return nil, err
+	}
+
+	segments := v.Segments()
+	var upper string
+	switch {
+	case segments[0] > 0:
+		upper = fmt.Sprintf("%d.0.0", segments[0]+1)
+	case segments[1] > 0:
+		upper = fmt.Sprintf("0.%d.0", segments[1]+1)
+	default:
+		upper = fmt.Sprintf("0.0.%d", segments[2]+1)
+	}
+
+	return []string{">= " + v.String(), "< " + upper}, nil
+}
+
+func isConstraintExpr(versionStr string) bool {
+	return strings.ContainsAny(versionStr, ",<>=!~^")
+}
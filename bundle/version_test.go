@@ -0,0 +1,96 @@
+package bundle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionExpr(t *testing.T) {
+	t.Run("Exact tag should be parsed as a concrete version", func(t *testing.T) {
+		v, err := ParseVersionExpr("v1.2.3")
+		require.NoError(t, err)
+		require.False(t, v.IsConstraint())
+		require.Equal(t, "v1.2.3", v.String())
+	})
+
+	t.Run("Pseudo version should be parsed as a concrete version", func(t *testing.T) {
+		v, err := ParseVersionExpr("v0.0.0+20240128102927-ab4647768668")
+		require.NoError(t, err)
+		require.False(t, v.IsConstraint())
+		require.True(t, v.IsPseudo())
+	})
+
+	t.Run("Constraint expression should keep its original form", func(t *testing.T) {
+		v, err := ParseVersionExpr(">= 1.0, < 2.0")
+		require.NoError(t, err)
+		require.True(t, v.IsConstraint())
+		require.Equal(t, ">= 1.0, < 2.0", v.String())
+	})
+
+	t.Run("Invalid constraint should fail", func(t *testing.T) {
+		_, err := ParseVersionExpr(">= one")
+		require.Error(t, err)
+	})
+}
+
+func TestVersionSpecMatch(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		version  string
+		expected bool
+	}{
+		{"~> 1.2", "v1.2.0", true},
+		{"~> 1.2", "v1.9.4", true},
+		{"~> 1.2", "v2.0.0", false},
+		{">= 1.0, < 2.0", "v1.5.0", true},
+		{">= 1.0, < 2.0", "v2.0.0", false},
+		{"^1.4", "v1.4.0", true},
+		{"^1.4", "v1.10.2", true},
+		{"^1.4", "v1.3.9", false},
+		{"^1.4", "v2.0.0", false},
+		{"^0.3.1", "v0.3.5", true},
+		{"^0.3.1", "v0.4.0", false},
+		{"^0.0.3", "v0.0.4", false},
+		{"v1.2.3", "v1.2.3", true},
+		{"v1.2.3", "v1.2.4", false},
+		{"~> 1.2", "v0.0.0+20240128102927-ab4647768668", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr+" "+tc.version, func(t *testing.T) {
+			required, err := ParseVersionExpr(tc.expr)
+			require.NoError(t, err)
+
+			v, err := ParseVersionExpr(tc.version)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expected, required.Match(v))
+		})
+	}
+}
+
+func TestVersionConstraintEqual(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{">= 1.0, < 2.0", ">= 1.0, < 2.0", true},
+		{">=1.0,<2.0", ">= 1.0, < 2.0", true},
+		{"< 2.0, >= 1.0", ">= 1.0, < 2.0", true},
+		{">= 1.0, < 2.0", ">= 1.0, < 3.0", false},
+		{"~> 1.2", "~> 1.3", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			a, err := ParseVersionExpr(tc.a)
+			require.NoError(t, err)
+
+			b, err := ParseVersionExpr(tc.b)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expected, a.Constraint.Equal(b.Constraint))
+		})
+	}
+}
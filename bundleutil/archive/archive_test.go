@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testFiles = map[string][]byte{
+	"bundle.hcl":        []byte("package {}\n"),
+	"policy/rules.rego": []byte("package policy.rules\n"),
+	"data.json":         []byte("{}"),
+}
+
+func TestWriteTarGz(t *testing.T) {
+	var first, second bytes.Buffer
+	require.NoError(t, WriteTarGz(&first, testFiles))
+	require.NoError(t, WriteTarGz(&second, testFiles))
+	require.Equal(t, first.Bytes(), second.Bytes(), "archive must be reproducible")
+
+	gr, err := gzip.NewReader(bytes.NewReader(first.Bytes()))
+	require.NoError(t, err)
+
+	names := make([]string, 0)
+	tr := tar.NewReader(gr)
+	for header, err := tr.Next(); err == nil; header, err = tr.Next() {
+		require.Zero(t, header.ModTime.Unix())
+		require.Zero(t, header.Uid)
+		require.Zero(t, header.Gid)
+		names = append(names, header.Name)
+	}
+	require.Equal(t, []string{"bundle.hcl", "data.json", "policy/rules.rego"}, names)
+
+	files, err := Read(first.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, testFiles, files)
+}
+
+func TestWriteZip(t *testing.T) {
+	var first, second bytes.Buffer
+	require.NoError(t, WriteZip(&first, testFiles))
+	require.NoError(t, WriteZip(&second, testFiles))
+	require.Equal(t, first.Bytes(), second.Bytes(), "archive must be reproducible")
+
+	files, err := Read(first.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, testFiles, files)
+}
+
+func TestReadInvalidPath(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteZip(&buf, map[string][]byte{"../outside": nil}))
+
+	_, err := ReadZip(buf.Bytes())
+	require.ErrorContains(t, err, "invalid file path")
+}
@@ -0,0 +1,101 @@
+package archive
+
+import (
+	"fmt"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/regoutil"
+)
+
+type archiveEncoder interface {
+	EncodeIgnoreFile(ignorefile *bundle.IgnoreFile) []byte
+	EncodeBundleFile(bundlefile *bundlefile.Schema) []byte
+	EncodeLockFile(lockfile *lockfile.Schema) []byte
+}
+
+type archiveDecoder interface {
+	DecodeBundleFile(content []byte) (*bundlefile.Schema, error)
+	DecodeIgnoreFile(content []byte) (*bundle.IgnoreFile, error)
+	DecodeLockFile(content []byte) (*lockfile.Schema, error)
+	Fileify(files map[string][]byte) (*encode.FileifyOutput, error)
+}
+
+// BundleFiles returns all files of the bundle by their paths
+// relative to the bundle root, the same way they are stored
+func BundleFiles(b *bundle.Bundle, encoder archiveEncoder) map[string][]byte {
+	files := make(map[string][]byte, len(b.RegoFiles)+len(b.OtherFiles)+3)
+
+	files[constant.BundleFileName] = encoder.EncodeBundleFile(b.BundleFile)
+	if b.LockFile != nil {
+		files[constant.LockFileName] = encoder.EncodeLockFile(b.LockFile)
+	}
+	if b.IgnoreFile != nil {
+		files[constant.IgnoreFileName] = encoder.EncodeIgnoreFile(b.IgnoreFile)
+	}
+
+	for path, f := range b.RegoFiles {
+		files[path] = f.Raw
+	}
+
+	for path, content := range b.OtherFiles {
+		files[path] = content
+	}
+
+	return files
+}
+
+// DecodeBundle builds the bundle from its files, the source and
+// version of the result are left for the caller to be filled in
+func DecodeBundle(files map[string][]byte, decoder archiveDecoder) (*bundle.Bundle, error) {
+	content, ok := files[constant.BundleFileName]
+	if !ok {
+		return nil, fmt.Errorf("file %s is not found", constant.BundleFileName)
+	}
+
+	bundleFile, err := decoder.DecodeBundleFile(content)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred while decoding %s content: %v", constant.BundleFileName, err)
+	}
+
+	var lockFile *lockfile.Schema
+	if content, ok := files[constant.LockFileName]; ok {
+		if lockFile, err = decoder.DecodeLockFile(content); err != nil {
+			return nil, fmt.Errorf("error occurred while decoding %s content: %v", constant.LockFileName, err)
+		}
+	}
+
+	ignoreFile := bundle.NewIgnoreFile()
+	if content, ok := files[constant.IgnoreFileName]; ok {
+		if ignoreFile, err = decoder.DecodeIgnoreFile(content); err != nil {
+			return nil, fmt.Errorf("error occurred while decoding %s content: %v", constant.IgnoreFileName, err)
+		}
+	}
+
+	if err := regoutil.PrepareDocumentParser(bundleFile); err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string][]byte, len(files))
+	for path, content := range files {
+		if !ignoreFile.Some(path) {
+			filtered[path] = content
+		}
+	}
+
+	fileifyOutput, err := decoder.Fileify(filtered)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bundle.Bundle{
+		BundleFile: bundlefile.PrepareSchema(bundleFile),
+		LockFile:   lockfile.PrepareSchema(lockFile),
+		RegoFiles:  fileifyOutput.RegoFiles,
+		IgnoreFile: ignoreFile,
+		OtherFiles: fileifyOutput.OtherFiles,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WriteTarGz writes the files into a gzip-compressed tar archive. The
+// result is reproducible: entries are sorted by name, and modification
+// time, owner and permissions are the same for every entry.
+func WriteTarGz(w io.Writer, files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Clean(name),
+			Mode:     0644,
+			Size:     int64(len(files[name])),
+			ModTime:  time.Unix(0, 0),
+			Format:   tar.FormatPAX,
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// ReadTarGz reads all regular files of the gzip-compressed tar archive,
+// with the same restrictions on paths and total size as `ReadZip`
+func ReadTarGz(content []byte) (map[string][]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar.gz archive: %v", err)
+	}
+	defer gr.Close()
+
+	var total int64
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar.gz archive: %v", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid file path '%s' in tar.gz archive", header.Name)
+		}
+
+		if _, exists := files[name]; exists {
+			return nil, fmt.Errorf("duplicate file '%s' in tar.gz archive", name)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, MaxSize-total+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file '%s' from tar.gz archive: %v", name, err)
+		}
+
+		total += int64(len(data))
+		if total > MaxSize {
+			return nil, fmt.Errorf("tar.gz archive exceeds maximum size of %d bytes", MaxSize)
+		}
+
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+// Read reads the files of either zip or gzip-compressed tar archive
+func Read(content []byte) (map[string][]byte, error) {
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		return ReadTarGz(content)
+	}
+
+	return ReadZip(content)
+}
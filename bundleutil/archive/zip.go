@@ -0,0 +1,97 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxSize is the maximum size of the unpacked archive contents
+const MaxSize = 64 << 20
+
+// zipModTime is used for all entries so that archives of the
+// same files are identical, zip format does not support earlier dates
+var zipModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteZip writes the files into a zip archive. Entries are sorted
+// by name and have fixed modification time, so that the result
+// depends only on the file names and contents.
+func WriteZip(w io.Writer, files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		header := &zip.FileHeader{
+			Name:     path.Clean(name),
+			Method:   zip.Deflate,
+			Modified: zipModTime,
+		}
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// ReadZip reads all files of the zip archive. Entries with
+// absolute paths or paths that lead outside of the archive root are
+// rejected, as well as archives that exceed `MaxSize` when unpacked.
+func ReadZip(content []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %v", err)
+	}
+
+	var total int64
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		name := path.Clean(f.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid file path '%s' in zip archive", f.Name)
+		}
+
+		if _, exists := files[name]; exists {
+			return nil, fmt.Errorf("duplicate file '%s' in zip archive", name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(io.LimitReader(rc, MaxSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file '%s' from zip archive: %v", name, err)
+		}
+
+		total += int64(len(data))
+		if total > MaxSize {
+			return nil, fmt.Errorf("zip archive exceeds maximum size of %d bytes", MaxSize)
+		}
+
+		files[name] = data
+	}
+
+	return files, nil
+}
@@ -12,13 +12,9 @@ import (
 	"github.com/4rchr4y/bpm/bundle/bundlefile"
 	"github.com/4rchr4y/bpm/bundle/lockfile"
 	"github.com/4rchr4y/bpm/bundle/regofile"
-	"github.com/4rchr4y/bpm/bundleutil"
 	"github.com/4rchr4y/bpm/constant"
 	"github.com/4rchr4y/bpm/core"
-	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclsimple"
-	"github.com/hashicorp/hcl/v2/hclsyntax"
-	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/open-policy-agent/opa/ast"
 )
 
@@ -71,27 +67,13 @@ func (e *Encoder) DecodeLockFile(content []byte) (*lockfile.Schema, error) {
 }
 
 func (e *Encoder) EncodeBundleFile(bundlefile *bundlefile.Schema) []byte {
-	f := hclwrite.NewEmptyFile()
-	gohcl.EncodeIntoBody(bundlefile, f.Body())
-
-	return bundleutil.FormatBundleFile(f.Bytes())
+	return bundlefile.Bytes()
 }
 
-const lockfileComment = "// This file has been auto-generated by `bpm`.\n// It is not meant to be edited manually."
-
-func (e *Encoder) EncodeLockFile(lockfile *lockfile.Schema) (result []byte) {
-	tempFile := hclwrite.NewEmptyFile()
-	gohcl.EncodeIntoBody(lockfile, tempFile.Body())
-
-	f := hclwrite.NewEmptyFile()
-
-	f.Body().AppendUnstructuredTokens([]*hclwrite.Token{
-		{Type: hclsyntax.TokenComment, Bytes: []byte(lockfileComment)},
-		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
-		{Type: hclsyntax.TokenOBrace, Bytes: tempFile.Bytes()},
-	})
+const lockfileComment = "// This file has been auto-generated by `bpm`.\n// It is not meant to be edited manually.\n"
 
-	return bundleutil.FormatLockFile(f.Bytes())
+func (e *Encoder) EncodeLockFile(lockfile *lockfile.Schema) []byte {
+	return append([]byte(lockfileComment), lockfile.Bytes()...)
 }
 
 func (e *Encoder) EncodeIgnoreFile(ignorefile *bundle.IgnoreFile) []byte {
@@ -0,0 +1,92 @@
+package encode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/stretchr/testify/require"
+)
+
+func readGolden(t *testing.T, name string) []byte {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	return content
+}
+
+func TestEncodeBundleFile(t *testing.T) {
+	encoder := new(Encoder)
+	golden := readGolden(t, "bundle.hcl")
+
+	t.Run("Decoded golden file should be encoded byte-for-byte", func(t *testing.T) {
+		schema, err := encoder.DecodeBundleFile(golden)
+		require.NoError(t, err)
+		require.Equal(t, string(golden), string(encoder.EncodeBundleFile(schema)))
+	})
+
+	t.Run("Encoding should be idempotent", func(t *testing.T) {
+		schema, err := encoder.DecodeBundleFile(golden)
+		require.NoError(t, err)
+
+		decoded, err := encoder.DecodeBundleFile(encoder.EncodeBundleFile(schema))
+		require.NoError(t, err)
+		require.Equal(t, schema, decoded)
+		require.Equal(t, string(golden), string(encoder.EncodeBundleFile(decoded)))
+	})
+
+	t.Run("Checksum should not depend on the formatting", func(t *testing.T) {
+		schema, err := encoder.DecodeBundleFile(golden)
+		require.NoError(t, err)
+
+		// the checksum of the golden file computed by earlier versions
+		// of bpm, changing it would invalidate existing lock files
+		require.Equal(t, "5b6773cfa733a8d9793ef256cd686311df046621324f72f0e4f06218ba783637", schema.Sum())
+	})
+
+	t.Run("Empty blocks should be written on a single line", func(t *testing.T) {
+		schema := bundlefile.PrepareSchema(&bundlefile.Schema{
+			Package: &bundlefile.PackageBlock{Name: "example", Repository: "example"},
+		})
+
+		expected := "package {\n  name        = \"example\"\n  author      = null\n  repository  = \"example\"\n  description = \"\"\n}\n\nworkspace {\n  internal = []\n  builtin  = []\n}\n\nrequire {}\n"
+		require.Equal(t, expected, string(encoder.EncodeBundleFile(schema)))
+	})
+}
+
+func TestEncodeLockFile(t *testing.T) {
+	encoder := new(Encoder)
+	golden := readGolden(t, "lockfile.hcl")
+
+	t.Run("Decoded golden file should be encoded byte-for-byte", func(t *testing.T) {
+		schema, err := encoder.DecodeLockFile(golden)
+		require.NoError(t, err)
+		require.Equal(t, string(golden), string(encoder.EncodeLockFile(schema)))
+	})
+
+	t.Run("Encoding should be idempotent", func(t *testing.T) {
+		schema, err := encoder.DecodeLockFile(golden)
+		require.NoError(t, err)
+
+		decoded, err := encoder.DecodeLockFile(encoder.EncodeLockFile(schema))
+		require.NoError(t, err)
+		require.Equal(t, schema, decoded)
+		require.Equal(t, string(golden), string(encoder.EncodeLockFile(decoded)))
+	})
+
+	t.Run("Strings similar to keywords and arrays should be kept", func(t *testing.T) {
+		schema, err := encoder.DecodeLockFile(golden)
+		require.NoError(t, err)
+
+		require.Equal(t, "github.com/example/a,b[c]", schema.Require.List[2].Source)
+		require.Equal(t, "public", schema.Require.List[2].Name)
+		require.Len(t, schema.Consist.List[0].Require, 2)
+	})
+
+	t.Run("Empty schema should be encoded", func(t *testing.T) {
+		expected := lockfileComment + "sum     = \"\"\nedition = \"2024\"\n\nconsist {}\n\nrequire {}\n"
+		require.Equal(t, expected, string(encoder.EncodeLockFile(lockfile.PrepareSchema(nil))))
+	})
+}
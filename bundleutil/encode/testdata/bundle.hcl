@@ -0,0 +1,22 @@
+package {
+  name        = "example"
+  author      = ["John Doe john@example.com", "Doe, Jane [maintainer]"]
+  repository  = "github.com/example/policies"
+  description = "Policies, rules [and] \"quoted\" data."
+}
+
+workspace {
+  internal = ["example.internal"]
+  builtin  = []
+}
+
+require {
+  bundle "github.com/example/base" {
+    name    = "base"
+    version = "v1.2.0"
+  }
+  bundle "github.com/example/public" {
+    name    = "public"
+    version = "v0.0.0+20240128102927-ab4647768668"
+  }
+}
@@ -0,0 +1,40 @@
+// This file has been auto-generated by `bpm`.
+// It is not meant to be edited manually.
+sum     = "5efce6798e96e8d79a5e29531c206f303700e6b6095a6114d876e5a933c383e7"
+edition = "2024"
+
+consist {
+  module "example.rules.main" public {
+    source = "rules/main.rego"
+    sum    = "459eaa993312ca44177ca573843aa71dd3ae885157ac04f292a31d753e0fb783"
+    require = [
+      "3:github.com/example/base@v1.2.0:base.rules",
+      "4:github.com/example/public@v0.0.0+20240128102927-ab4647768668:public.lib",
+    ]
+  }
+  module "example.internal" private {
+    source = "internal.rego"
+    sum    = "d973b71fd6dd925b1b8a1cd5e1e8e7c4e3b9a5c4b7c8e2f0a1d2c3b4a5f6e7d8"
+  }
+}
+
+require {
+  bundle "github.com/example/base" direct {
+    name    = "base"
+    version = "v1.2.0"
+    h1      = "0a1b2c3d"
+    h2      = "4e5f6a7b"
+  }
+  bundle "github.com/example/public" direct {
+    name    = "public"
+    version = "v0.0.0+20240128102927-ab4647768668"
+    h1      = "8c9d0e1f"
+    h2      = "2a3b4c5d"
+  }
+  bundle "github.com/example/a,b[c]" indirect {
+    name    = "public"
+    version = "v2.0.0"
+    h1      = "6e7f8a9b"
+    h2      = "0c1d2e3f"
+  }
+}
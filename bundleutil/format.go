@@ -1,59 +1,127 @@
-// TODO: rewrite formatting based on AST rather than regular expressions
-
 package bundleutil
 
 import (
-	"bytes"
-	"fmt"
-	"regexp"
-	"strings"
-
-	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
 )
 
-var (
-	// regex expression for formatting arrays
-	arrayFormatterRegex *regexp.Regexp = regexp.MustCompile(`(\[\s*|\,\s*|\s*\])`)
-	// regex to find and replace extra newlines after {
-	bracketNormalizerRegex *regexp.Regexp = regexp.MustCompile(`(?m){\s*\n+`)
-	// regex to remove quotes around keywords
-	lockfileKeywordNormalizerRegex *regexp.Regexp
-)
+// HCLBuilder emits the tokens of an HCL file one element at a time, so that
+// the layout of manifest files is defined in a single place. Indentation
+// and alignment of the result are left to the `hclwrite` formatter.
+type HCLBuilder struct {
+	tokens hclwrite.Tokens
+}
 
-func init() {
-	lockfileKeywordNormalizerPattern := fmt.Sprintf(`"(%s)"`, joinKeywordList("|", lockfile.Keywords[:]))
-	lockfileKeywordNormalizerRegex = regexp.MustCompile(lockfileKeywordNormalizerPattern)
+// Newline adds an empty line
+func (b *HCLBuilder) Newline() {
+	b.tokens = append(b.tokens, newlineToken())
 }
 
-// FormatBundleFile designed for post-processing and bundlefile formatting
-func FormatBundleFile(content []byte) []byte {
-	content = bytes.TrimSpace(content)
-	content = bracketNormalizerRegex.ReplaceAll(content, []byte("{\n"))
-	content = bytes.Replace(content, []byte("{\n}"), []byte("{}"), -1)
+// Attribute adds an attribute with the value on a single line
+func (b *HCLBuilder) Attribute(name string, value cty.Value) {
+	b.tokens = append(b.tokens, hclwrite.TokensForIdentifier(name)...)
+	b.tokens = append(b.tokens, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte("=")})
+	b.tokens = append(b.tokens, hclwrite.TokensForValue(value)...)
+	b.tokens = append(b.tokens, newlineToken())
+}
+
+// ListAttribute adds a list of strings attribute with every
+// item on a separate line, followed by a trailing comma
+func (b *HCLBuilder) ListAttribute(name string, values []string) {
+	if len(values) == 0 {
+		b.Attribute(name, cty.ListValEmpty(cty.String))
+		return
+	}
+
+	b.tokens = append(b.tokens, hclwrite.TokensForIdentifier(name)...)
+	b.tokens = append(b.tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte("=")},
+		&hclwrite.Token{Type: hclsyntax.TokenOBrack, Bytes: []byte("[")},
+		newlineToken(),
+	)
+
+	for _, v := range values {
+		b.tokens = append(b.tokens, hclwrite.TokensForValue(cty.StringVal(v))...)
+		b.tokens = append(b.tokens,
+			&hclwrite.Token{Type: hclsyntax.TokenComma, Bytes: []byte(",")},
+			newlineToken(),
+		)
+	}
 
-	return content
+	b.tokens = append(b.tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenCBrack, Bytes: []byte("]")},
+		newlineToken(),
+	)
 }
 
-// FormatBundleFile designed for post-processing and lockfile formatting
-func FormatLockFile(content []byte) []byte {
-	content = bytes.TrimSpace(content)
-	content = bracketNormalizerRegex.ReplaceAll(content, []byte("{\n"))
-	content = arrayFormatterRegex.ReplaceAllFunc(content, func(match []byte) []byte {
-		switch {
-		case bytes.Contains(match, []byte("[")):
-			return []byte("[\n\t\t\t")
-
-		case bytes.Contains(match, []byte("]")):
-			return []byte("\n\t\t]")
-
-		default:
-			return []byte(",\n\t\t\t")
-		}
-	})
-	content = lockfileKeywordNormalizerRegex.ReplaceAll(content, []byte("$1"))
-	content = bytes.Replace(content, []byte("{\n}"), []byte("{}"), -1)
-
-	return content
+// Block adds a block with the body produced by the function,
+// a block with an empty body is written on a single line
+func (b *HCLBuilder) Block(typeName string, labels []hclwrite.Tokens, body func(b *HCLBuilder)) {
+	b.tokens = append(b.tokens, hclwrite.TokensForIdentifier(typeName)...)
+	for _, label := range labels {
+		b.tokens = append(b.tokens, label...)
+	}
+
+	inner := new(HCLBuilder)
+	if body != nil {
+		body(inner)
+	}
+
+	b.tokens = append(b.tokens, &hclwrite.Token{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")})
+	if len(inner.tokens) > 0 {
+		b.tokens = append(b.tokens, newlineToken())
+		b.tokens = append(b.tokens, inner.tokens...)
+	}
+
+	b.tokens = append(b.tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte("}")},
+		newlineToken(),
+	)
+}
+
+// Empty reports whether nothing has been added yet
+func (b *HCLBuilder) Empty() bool { return len(b.tokens) == 0 }
+
+// Bytes returns the formatted content of the file
+func (b *HCLBuilder) Bytes() []byte {
+	f := hclwrite.NewEmptyFile()
+	f.Body().AppendUnstructuredTokens(b.tokens)
+
+	return f.Bytes()
+}
+
+// StringLabel returns the tokens of a quoted block label
+func StringLabel(label string) hclwrite.Tokens {
+	return hclwrite.TokensForValue(cty.StringVal(label))
+}
+
+// KeywordLabel returns the tokens of a block label written without quotes,
+// labels that are not valid identifiers are still quoted
+func KeywordLabel(label string) hclwrite.Tokens {
+	if !hclsyntax.ValidIdentifier(label) {
+		return StringLabel(label)
+	}
+
+	return hclwrite.TokensForIdentifier(label)
+}
+
+// StringList returns the value of a list of strings attribute the same way
+// as `gohcl` encodes it, i.e. a nil list is encoded as null
+func StringList(values []string) cty.Value {
+	switch {
+	case values == nil:
+		return cty.NullVal(cty.List(cty.String))
+	case len(values) == 0:
+		return cty.ListValEmpty(cty.String)
+	}
+
+	list := make([]cty.Value, len(values))
+	for i := range values {
+		list[i] = cty.StringVal(values[i])
+	}
+
+	return cty.ListVal(list)
 }
 
 // FormatBundleFile designed to create a single place for forming a string
@@ -62,15 +130,6 @@ func FormatSourceWithVersion(source, version string) string {
 	return source + "@" + version
 }
 
-func joinKeywordList(separator string, keywords []string) string {
-	var builder strings.Builder
-
-	for i, keyword := range keywords {
-		if i > 0 {
-			builder.WriteString(separator)
-		}
-		builder.WriteString(keyword)
-	}
-
-	return builder.String()
+func newlineToken() *hclwrite.Token {
+	return &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")}
 }
@@ -0,0 +1,115 @@
+package inspect
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/constant"
+)
+
+// Mismatch describes a file whose checksum differs from the recorded one
+type Mismatch struct {
+	File     string // file path relative to the bundle root, empty if the file is unknown
+	Expected string // recorded checksum, empty if the file is not recorded
+	Actual   string // computed checksum, empty if the file is missing
+}
+
+func (m *Mismatch) String() string {
+	switch {
+	case m.File == "":
+		return fmt.Sprintf("checksum of files not covered by the module list does not match\n\t> expected: %s\n\t> actual: %s", m.Expected, m.Actual)
+	case m.Expected == "":
+		return fmt.Sprintf("%s: file is not recorded in %s", m.File, constant.LockFileName)
+	case m.Actual == "":
+		return fmt.Sprintf("%s: file is missing", m.File)
+	default:
+		return fmt.Sprintf("%s: checksum does not match\n\t> expected: %s\n\t> actual: %s", m.File, m.Expected, m.Actual)
+	}
+}
+
+// CheckRequirement recomputes the checksums of the required bundle and
+// compares them with the ones recorded for it in the lock file of the
+// parent bundle. If the overall checksum does not match, the files that
+// caused it are located using the module checksums of the bundle itself.
+func (insp *Inspector) CheckRequirement(b *bundle.Bundle, r *lockfile.RequirementDecl) []*Mismatch {
+	result := make([]*Mismatch, 0)
+
+	if h1 := b.BundleFile.Sum(); h1 != r.H1 {
+		result = append(result, &Mismatch{File: constant.BundleFileName, Expected: r.H1, Actual: h1})
+	}
+
+	h2 := b.Sum()
+	if h2 == r.H2 {
+		return result
+	}
+
+	modules := insp.CheckModules(b)
+	if len(result) == 0 && len(modules) == 0 {
+		// only the files that have no checksums of their own could have caused it
+		return []*Mismatch{{Expected: r.H2, Actual: h2}}
+	}
+
+	return append(result, modules...)
+}
+
+// CheckModules compares the checksums of the rego files of the bundle
+// with the checksums of the modules recorded in its own lock file
+func (insp *Inspector) CheckModules(b *bundle.Bundle) []*Mismatch {
+	result := make([]*Mismatch, 0)
+	recorded := make(map[string]string)
+
+	if b.LockFile != nil && b.LockFile.Consist != nil {
+		for _, m := range b.LockFile.Consist.List {
+			recorded[m.Source] = m.Sum
+
+			f, exists := b.RegoFiles[m.Source]
+			if !exists {
+				result = append(result, &Mismatch{File: m.Source, Expected: m.Sum})
+				continue
+			}
+
+			if sum := f.Sum(); sum != m.Sum {
+				result = append(result, &Mismatch{File: m.Source, Expected: m.Sum, Actual: sum})
+			}
+		}
+	}
+
+	for filePath, f := range b.RegoFiles {
+		if _, exists := recorded[filePath]; !exists {
+			result = append(result, &Mismatch{File: filePath, Actual: f.Sum()})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].File < result[j].File
+	})
+
+	return result
+}
+
+// CheckBundle compares the checksums of the bundle with
+// the checksums recorded in its own lock file
+func (insp *Inspector) CheckBundle(b *bundle.Bundle) []*Mismatch {
+	sum := b.Sum()
+	if sum == b.LockFile.Sum {
+		return nil
+	}
+
+	if modules := insp.CheckModules(b); len(modules) > 0 {
+		return modules
+	}
+
+	return []*Mismatch{{Expected: b.LockFile.Sum, Actual: sum}}
+}
+
+// Report prints every mismatch of the bundle with the given
+// name and reports whether the bundle has no mismatches
+func (insp *Inspector) Report(name string, mismatches []*Mismatch) bool {
+	for _, m := range mismatches {
+		insp.IO.PrintfErr("%s: %s", name, m)
+	}
+
+	return len(mismatches) == 0
+}
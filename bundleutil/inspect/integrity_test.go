@@ -0,0 +1,84 @@
+package inspect
+
+import (
+	"io"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBundle(t *testing.T, files map[string][]byte) *bundle.Bundle {
+	encoder := &encode.Encoder{IO: iostream.NewIOStream(iostream.WithOutput(io.Discard))}
+
+	b, err := archive.DecodeBundle(files, encoder)
+	require.NoError(t, err)
+
+	// record the checksums the same way the lock file synchronization does
+	b.LockFile.Sum = b.Sum()
+	for filePath, f := range b.RegoFiles {
+		b.LockFile.Consist.List = append(b.LockFile.Consist.List, &lockfile.ModuleDecl{
+			Package: f.Package(),
+			Source:  filePath,
+			Sum:     f.Sum(),
+		})
+	}
+
+	return b
+}
+
+func testFiles() map[string][]byte {
+	return map[string][]byte{
+		constant.BundleFileName: []byte("package {\n  name       = \"dep\"\n  repository = \"example.com/dep\"\n}\n"),
+		"lib.rego":              []byte("package dep.lib\n\nlimit := 10\n"),
+		"data.json":             []byte(`{"limit": 10}`),
+	}
+}
+
+func TestCheckRequirement(t *testing.T) {
+	insp := &Inspector{}
+	original := newTestBundle(t, testFiles())
+	r := &lockfile.RequirementDecl{H1: original.BundleFile.Sum(), H2: original.Sum()}
+
+	require.Empty(t, insp.CheckRequirement(original, r))
+
+	t.Run("Modified rego file should be reported", func(t *testing.T) {
+		files := testFiles()
+		files["lib.rego"] = []byte("package dep.lib\n\nlimit := 1000\n")
+
+		b := newTestBundle(t, files)
+		b.LockFile = original.LockFile
+
+		mismatches := insp.CheckRequirement(b, r)
+		require.Len(t, mismatches, 1)
+		require.Equal(t, "lib.rego", mismatches[0].File)
+	})
+
+	t.Run("Added rego file should be reported", func(t *testing.T) {
+		files := testFiles()
+		files["extra.rego"] = []byte("package dep.extra\n")
+
+		b := newTestBundle(t, files)
+		b.LockFile = original.LockFile
+
+		mismatches := insp.CheckRequirement(b, r)
+		require.Len(t, mismatches, 1)
+		require.Equal(t, "extra.rego", mismatches[0].File)
+		require.Empty(t, mismatches[0].Expected)
+	})
+
+	t.Run("Modified data file should be reported", func(t *testing.T) {
+		files := testFiles()
+		files["data.json"] = []byte(`{"limit": 1000}`)
+
+		mismatches := insp.CheckRequirement(newTestBundle(t, files), r)
+		require.Len(t, mismatches, 1)
+		require.Empty(t, mismatches[0].File)
+		require.Equal(t, r.H2, mismatches[0].Expected)
+	})
+}
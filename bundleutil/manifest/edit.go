@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// editBundleFile applies the requirements of the schema to the existing
+// bundle file contents. Only `bundle` blocks of the `require` block that
+// differ from the schema are inserted, updated or removed, so comments,
+// ordering and formatting of everything else stay untouched.
+func editBundleFile(content []byte, schema *bundlefile.Schema) ([]byte, error) {
+	f, diags := hclwrite.ParseConfig(content, constant.BundleFileName, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %v", constant.BundleFileName, diags)
+	}
+
+	var requireList []*bundlefile.RequirementDecl
+	if schema.Require != nil {
+		requireList = schema.Require.List
+	}
+
+	// nested blocks must start on a separate line, even if
+	// the existing block is written as `require {}`
+	inline := false
+
+	requireBlock := findBlock(f.Body(), "require")
+	if requireBlock == nil {
+		if len(requireList) == 0 {
+			return content, nil
+		}
+
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			f.Body().AppendNewline()
+		}
+
+		f.Body().AppendNewline()
+		requireBlock = f.Body().AppendNewBlock("require", nil)
+	} else {
+		inline = len(requireBlock.Body().BuildTokens(nil)) == 0
+	}
+
+	wanted := make(map[string]*bundlefile.RequirementDecl, len(requireList))
+	for _, r := range requireList {
+		wanted[r.Source] = r
+	}
+
+	existing := make(map[string]struct{}, len(requireList))
+	for _, block := range requireBlock.Body().Blocks() {
+		labels := block.Labels()
+		if block.Type() != "bundle" || len(labels) != 1 {
+			continue
+		}
+
+		r, isWanted := wanted[labels[0]]
+		if _, isDuplicate := existing[labels[0]]; !isWanted || isDuplicate {
+			requireBlock.Body().RemoveBlock(block)
+			continue
+		}
+
+		existing[labels[0]] = struct{}{}
+		setStringAttribute(block.Body(), "name", r.Name)
+		setStringAttribute(block.Body(), "version", r.Version)
+	}
+
+	for _, r := range requireList {
+		if _, exists := existing[r.Source]; exists {
+			continue
+		}
+
+		if inline {
+			requireBlock.Body().AppendNewline()
+			inline = false
+		}
+
+		block := requireBlock.Body().AppendNewBlock("bundle", []string{r.Source})
+		block.Body().SetAttributeValue("name", cty.StringVal(r.Name))
+		block.Body().SetAttributeValue("version", cty.StringVal(r.Version))
+	}
+
+	// a block left without requirements is collapsed back to `require {}`,
+	// unless the user has left some comments inside of it
+	if isBlank(requireBlock.Body()) {
+		requireBlock.Body().Clear()
+	}
+
+	return f.Bytes(), nil
+}
+
+func isBlank(body *hclwrite.Body) bool {
+	for _, token := range body.BuildTokens(nil) {
+		if token.Type != hclsyntax.TokenNewline {
+			return false
+		}
+	}
+
+	return true
+}
+
+func findBlock(body *hclwrite.Body, typeName string) *hclwrite.Block {
+	for _, block := range body.Blocks() {
+		if block.Type() == typeName {
+			return block
+		}
+	}
+
+	return nil
+}
+
+// setStringAttribute updates the attribute only if its value has actually
+// changed, so that the way the value is written by the user is preserved
+func setStringAttribute(body *hclwrite.Body, name, value string) {
+	expected := hclwrite.TokensForValue(cty.StringVal(value)).Bytes()
+	if attr := body.GetAttribute(name); attr != nil {
+		if bytes.Equal(bytes.TrimSpace(attr.Expr().BuildTokens(nil).Bytes()), expected) {
+			return
+		}
+	}
+
+	body.SetAttributeValue(name, cty.StringVal(value))
+}
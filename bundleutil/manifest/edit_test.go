@@ -0,0 +1,98 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/stretchr/testify/require"
+)
+
+const testBundleFile = `# policies of the example team
+package {
+  name       = "example"
+  repository = "github.com/example/policies"
+}
+
+require {
+  # pinned until the new rule format is supported
+  bundle "github.com/example/base" {
+    name    = "base"
+    version = "v1.2.0" # do not upgrade
+  }
+
+  bundle "github.com/example/old" {
+    name    = "old"
+    version = "v0.1.0"
+  }
+}
+`
+
+func testSchema(list ...*bundlefile.RequirementDecl) *bundlefile.Schema {
+	return &bundlefile.Schema{
+		Require: &bundlefile.RequireBlock{List: list},
+	}
+}
+
+func TestEditBundleFile(t *testing.T) {
+	base := &bundlefile.RequirementDecl{Source: "github.com/example/base", Name: "base", Version: "v1.2.0"}
+	old := &bundlefile.RequirementDecl{Source: "github.com/example/old", Name: "old", Version: "v0.1.0"}
+
+	t.Run("Unchanged requirements should keep the file as is", func(t *testing.T) {
+		result, err := editBundleFile([]byte(testBundleFile), testSchema(base, old))
+		require.NoError(t, err)
+		require.Equal(t, testBundleFile, string(result))
+	})
+
+	t.Run("Removed requirement should only drop its block", func(t *testing.T) {
+		result, err := editBundleFile([]byte(testBundleFile), testSchema(base))
+		require.NoError(t, err)
+		require.Contains(t, string(result), "# pinned until the new rule format is supported")
+		require.Contains(t, string(result), `version = "v1.2.0" # do not upgrade`)
+		require.NotContains(t, string(result), "github.com/example/old")
+	})
+
+	t.Run("Updated requirement should keep the comments", func(t *testing.T) {
+		updated := &bundlefile.RequirementDecl{Source: base.Source, Name: base.Name, Version: "v1.3.0"}
+
+		result, err := editBundleFile([]byte(testBundleFile), testSchema(updated, old))
+		require.NoError(t, err)
+		require.Contains(t, string(result), "# pinned until the new rule format is supported")
+		require.Contains(t, string(result), `version = "v1.3.0" # do not upgrade`)
+	})
+
+	t.Run("New requirement should be appended to the require block", func(t *testing.T) {
+		added := &bundlefile.RequirementDecl{Source: "github.com/example/new", Name: "new", Version: "v2.0.0"}
+
+		result, err := editBundleFile([]byte(testBundleFile), testSchema(base, old, added))
+		require.NoError(t, err)
+		require.Contains(t, string(result), testBundleFile[:len(testBundleFile)-2])
+		require.Contains(t, string(result), "  bundle \"github.com/example/new\" {\n    name    = \"new\"\n    version = \"v2.0.0\"\n  }\n}\n")
+	})
+
+	t.Run("Missing or empty require block should be created", func(t *testing.T) {
+		added := &bundlefile.RequirementDecl{Source: "github.com/example/new", Name: "new", Version: "v2.0.0"}
+		expected := "package {}\n\nrequire {\n  bundle \"github.com/example/new\" {\n    name    = \"new\"\n    version = \"v2.0.0\"\n  }\n}\n"
+
+		for _, content := range []string{"package {}", "package {}\n\nrequire {}\n"} {
+			result, err := editBundleFile([]byte(content), testSchema(added))
+			require.NoError(t, err)
+			require.Equal(t, expected, string(result))
+		}
+	})
+
+	t.Run("Require block should be collapsed when the last requirement is removed", func(t *testing.T) {
+		content := "require {\n  bundle \"github.com/example/old\" {\n    name    = \"old\"\n    version = \"v0.1.0\"\n  }\n}\n"
+
+		result, err := editBundleFile([]byte(content), testSchema())
+		require.NoError(t, err)
+		require.Equal(t, "require {}\n", string(result))
+	})
+
+	t.Run("Empty require block should be kept if nothing is added", func(t *testing.T) {
+		content := "package {}\n\nrequire {}\n"
+
+		result, err := editBundleFile([]byte(content), testSchema())
+		require.NoError(t, err)
+		require.Equal(t, content, string(result))
+	})
+}
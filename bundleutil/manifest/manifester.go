@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -16,7 +18,10 @@ import (
 	"github.com/4rchr4y/bpm/constant"
 	"github.com/4rchr4y/bpm/core"
 	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/4rchr4y/bpm/pkg/resolver"
 	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
+	"github.com/hashicorp/go-version"
 )
 
 var compOp = map[bool]string{true: "=>", false: "<="}
@@ -31,7 +36,7 @@ func NewBundlefileRequirementDecl(b *bundle.Bundle) *bundlefile.RequirementDecl
 
 func NewLockfileRequirementDecl(b *bundle.Bundle, direction lockfile.DirectionType) *lockfile.RequirementDecl {
 	return &lockfile.RequirementDecl{
-		Source:    b.Source,
+		Source:    remote.Redact(b.Source), // credentials must never be stored in the lock file
 		Direction: direction.String(),
 		Name:      b.Name(),
 		Version:   b.Version.String(),
@@ -54,14 +59,20 @@ type manifesterStorage interface {
 
 type manifesterFetcher interface {
 	Fetch(ctx context.Context, source string, version *bundle.VersionSpec) (*fetch.FetchOutput, error)
+	ListVersions(ctx context.Context, source string) ([]*version.Version, error)
+}
+
+type manifesterResolver interface {
+	Resolve(ctx context.Context, root *bundle.Bundle) (*resolver.Resolution, error)
 }
 
 type Manifester struct {
-	IO      core.IO
-	OSWrap  osiface.OSWrapper
-	Storage manifesterStorage
-	Encoder manifesterEncoder
-	Fetcher manifesterFetcher
+	IO       core.IO
+	OSWrap   osiface.OSWrapper
+	Storage  manifesterStorage
+	Encoder  manifesterEncoder
+	Fetcher  manifesterFetcher
+	Resolver manifesterResolver
 }
 
 type InsertRequirementInput struct {
@@ -79,9 +90,9 @@ func (m *Manifester) InsertRequirement(ctx context.Context, input *InsertRequire
 		bundlefile.FilterBySource(input.Source),
 	)
 
-	if ok && existingRequirement.Version == input.Version.String() {
+	if ok && sameDeclaredVersion(existingRequirement.Version, input.Version) {
 		m.IO.PrintfOk("bundle %s is already installed",
-			bundleutil.FormatSourceWithVersion(input.Source, input.Version.String()),
+			bundleutil.FormatSourceWithVersion(input.Source, existingRequirement.Version),
 		)
 		return m.SyncLockfile(ctx, input.Parent) // such requirement is already installed, then just synchronize
 	}
@@ -91,12 +102,29 @@ func (m *Manifester) InsertRequirement(ctx context.Context, input *InsertRequire
 		return err
 	}
 
+	decl := NewBundlefileRequirementDecl(result.Target)
+	if input.Version.IsConstraint() {
+		// the constraint itself is kept in the bundle file, while the
+		// resolved concrete version is going to be recorded in the lock file
+		decl.Version = input.Version.String()
+	}
+
 	if ok {
 		existingVersion, err := bundle.ParseVersionExpr(existingRequirement.Version)
 		if err != nil {
 			return err
 		}
 
+		if existingVersion.IsConstraint() || input.Version.IsConstraint() {
+			m.IO.PrintfInfo("upgrading %s => %s",
+				bundleutil.FormatSourceWithVersion(input.Source, existingRequirement.Version),
+				bundleutil.FormatSourceWithVersion(input.Source, decl.Version),
+			)
+
+			input.Parent.BundleFile.Require.List[idx] = decl
+			return m.SyncLockfile(ctx, input.Parent)
+		}
+
 		if result.Target.Version.String() == existingVersion.String() {
 			m.IO.PrintfOk("bundle %s is already installed",
 				bundleutil.FormatSourceWithVersion(result.Target.Repository(), result.Target.Version.String()),
@@ -117,20 +145,32 @@ func (m *Manifester) InsertRequirement(ctx context.Context, input *InsertRequire
 			bundleutil.FormatSourceWithVersion(result.Target.Repository(), result.Target.Version.String()),
 		)
 
-		input.Parent.BundleFile.Require.List[idx] = NewBundlefileRequirementDecl(result.Target)
+		input.Parent.BundleFile.Require.List[idx] = decl
 		// input.Parent.BundleFile.Workspace.Builtin = syncBuiltinList(input.Parent, result.Target)
 		return m.SyncLockfile(ctx, input.Parent)
 	}
 
-	input.Parent.BundleFile.Require.List = append(
-		input.Parent.BundleFile.Require.List,
-		NewBundlefileRequirementDecl(result.Target),
-	)
+	input.Parent.BundleFile.Require.List = append(input.Parent.BundleFile.Require.List, decl)
 	// input.Parent.BundleFile.Workspace.Builtin = syncBuiltinList(input.Parent, result.Target)
 
 	return m.SyncLockfile(ctx, input.Parent)
 }
 
+// sameDeclaredVersion reports whether the declared version expression
+// requests the same version, constraints are compared by their meaning
+func sameDeclaredVersion(declared string, v *bundle.VersionSpec) bool {
+	if declared == v.String() {
+		return true
+	}
+
+	existing, err := bundle.ParseVersionExpr(declared)
+	if err != nil {
+		return false
+	}
+
+	return existing.IsConstraint() && v.IsConstraint() && existing.Constraint.Equal(v.Constraint)
+}
+
 func syncBuiltinList(actual, coming *bundle.Bundle) []string {
 	result := make([]string, 0)
 	cache := make(map[string]struct{}, 0)
@@ -154,82 +194,59 @@ func syncBuiltinList(actual, coming *bundle.Bundle) []string {
 }
 
 func (m *Manifester) SyncLockfile(ctx context.Context, parent *bundle.Bundle) error {
-	// creating a cache for faster matching with bundle file
-	requireCache := make(map[string]struct{})
-
-	for i, req := range parent.LockFile.Require.List {
-		// while going through the lock requirements, simultaneously
-		// remove requirements that no longer exist in bundle file
-		if exists := parent.BundleFile.SomeRequirement(
-			bundlefile.FilterBySource(req.Source),
-			bundlefile.FilterByVersion(req.Version),
-		); !exists {
-			parent.LockFile.Require.List[i] = nil
-			continue
-		}
-
-		// creating a cache of lock requirements
-		formattedVersion := bundleutil.FormatSourceWithVersion(req.Source, req.Version)
-		requireCache[formattedVersion] = struct{}{}
+	return m.syncLockfile(ctx, parent, nil)
+}
 
+// syncLockfile synchronizes the lock file, imports of the removed
+// bundles are skipped instead of being reported as undefined
+func (m *Manifester) syncLockfile(ctx context.Context, parent *bundle.Bundle, removed map[string]struct{}) error {
+	resolution, err := m.Resolver.Resolve(ctx, parent)
+	if err != nil {
+		return err
 	}
 
 	// list of all bundles required for the bath bundle,
 	// it is necessary for in-depth comparison of imports
-	requireList := make(map[string]*bundle.Bundle, 0)
+	requireList := make(map[string]*bundle.Bundle, len(resolution.Direct))
 
-	// go through all direct requirements to ensure that the
-	// lock file is up to date
-	for _, r := range parent.BundleFile.Require.List {
-		v, _ := bundle.ParseVersionExpr(r.Version)
-		// ignore the error, because if this is a local bundle,
-		// then it will be impossible to get the version from it
-		// and it will always be equal to the `latest`
+	// the lock file requirements are fully rebuilt from the resolution
+	// result, so requirements that no longer exist are dropped as well
+	lockList := make([]*lockfile.RequirementDecl, 0, len(resolution.Direct)+len(resolution.Indirect))
 
-		result, err := m.Fetcher.Fetch(ctx, r.Source, v)
-		if err != nil {
-			return err
-		}
+	for _, b := range resolution.Direct {
+		requireList[b.Name()] = b
+		lockList = append(lockList, NewLockfileRequirementDecl(b, lockfile.Direct))
+	}
 
-		requireList[result.Target.Name()] = result.Target
+	for _, b := range resolution.Indirect {
+		lockList = append(lockList, NewLockfileRequirementDecl(b, lockfile.Indirect))
+	}
 
-		directionFn := func(b *bundle.Bundle) lockfile.DirectionType {
-			if b.BundleFile.Package.Repository == result.Target.BundleFile.Package.Repository {
-				return lockfile.Direct
-			} else {
-				return lockfile.Indirect
-			}
+	for _, b := range resolution.List() {
+		// cannot save a bundle without a version, since
+		// it is impossible to obtain it correctly later
+		if b.Version == nil {
+			continue
 		}
 
-		for _, b := range result.Merge() {
-			// cannot save a bundle without a version, since
-			// it is impossible to obtain it correctly later
-			if v != nil {
-				if err := m.Storage.StoreSome(b); err != nil {
-					return err
-				}
-			}
-
-			key := bundleutil.FormatSourceWithVersion(b.Source, b.Version.String())
-			if _, exists := requireCache[key]; !exists {
-				decl := NewLockfileRequirementDecl(b, directionFn(b))
-				parent.LockFile.Require.List = append(parent.LockFile.Require.List, decl)
-			}
+		if err := m.Storage.StoreSome(b); err != nil {
+			return err
 		}
 	}
 
-	modules, err := m.prepareModuleList(parent, requireList)
+	modules, err := m.prepareModuleList(parent, requireList, removed)
 	if err != nil {
 		return err
 	}
 
+	parent.LockFile.Require.List = lockList
 	parent.LockFile.Sum = parent.Sum()
 	parent.LockFile.Consist = &lockfile.ConsistBlock{List: modules}
 	return nil
 }
 
 // prepareRequireList prepares the value of block `modules` in lockfile
-func (m *Manifester) prepareModuleList(b *bundle.Bundle, requireList map[string]*bundle.Bundle) ([]*lockfile.ModuleDecl, error) {
+func (m *Manifester) prepareModuleList(b *bundle.Bundle, requireList map[string]*bundle.Bundle, removed map[string]struct{}) ([]*lockfile.ModuleDecl, error) {
 	result := make([]*lockfile.ModuleDecl, 0, len(b.RegoFiles))
 
 	// make a map of all private modules
@@ -242,6 +259,7 @@ func (m *Manifester) prepareModuleList(b *bundle.Bundle, requireList map[string]
 	prepareInput := &prepareRequireListInput{
 		FileSet:     b.RegoFiles,
 		RequireList: requireList,
+		Removed:     removed,
 		Builtin: func() map[string]struct{} {
 			result := make(map[string]struct{}, len(b.BundleFile.Workspace.Builtin))
 
@@ -284,11 +302,17 @@ func (m *Manifester) prepareModuleList(b *bundle.Bundle, requireList map[string]
 	return result, nil
 }
 
+// importFilePath returns the path of the bundle file the import would refer to
+func importFilePath(importPath string) string {
+	return strings.Replace(importPath, ".", "/", -1) + constant.RegoFileExt
+}
+
 type prepareRequireListInput struct {
 	File        *regofile.File            // directly checked file
 	FileSet     map[string]*regofile.File // a set of files for the entire bundle
 	RequireList map[string]*bundle.Bundle // list of all registered requirements
 	Builtin     map[string]struct{}       // list of all built-in imports
+	Removed     map[string]struct{}       // names of removed bundles that can still be imported
 }
 
 // prepareRequireList prepares the value of field `require` in lockfile modules list
@@ -316,8 +340,7 @@ func (m *Manifester) prepareRequireList(input *prepareRequireListInput) (result
 		}
 
 		// checking whether the specified import is a link to a file that exists locally within the bundle
-		filePath := strings.Replace(importPath, ".", "/", -1) + constant.RegoFileExt
-		_, existsAsFile := input.FileSet[filePath]
+		_, existsAsFile := input.FileSet[importFilePath(importPath)]
 		if existsAsFile {
 			continue
 		}
@@ -328,6 +351,11 @@ func (m *Manifester) prepareRequireList(input *prepareRequireListInput) (result
 			continue
 		}
 
+		// imports of a forcibly removed bundle are left as is
+		if _, isRemoved := input.Removed[packageName]; isRemoved {
+			continue
+		}
+
 		// checking that the package used really existsAsBundle for this bundle
 		required, existsAsBundle := input.RequireList[packageName]
 		if !existsAsBundle && !existsAsFile && !existsAsBuiltin {
@@ -350,12 +378,22 @@ func (m *Manifester) prepareRequireList(input *prepareRequireListInput) (result
 	return result, nil
 }
 
+// Upgrade writes the bundle file and the lock file of the bundle. Each file
+// is written into a temporary file first and then renamed, so that a failure
+// never leaves a partially written file behind. If the lock file cannot be
+// replaced, the previous bundle file is restored.
 func (m *Manifester) Upgrade(workDir string, b *bundle.Bundle) error {
-	if err := m.upgradeBundleFile(workDir, b); err != nil {
+	bundlefileBytes, err := m.encodeBundleFile(workDir, b)
+	if err != nil {
 		return err
 	}
 
-	if err := m.upgradeLockFile(workDir, b); err != nil {
+	files := []*pendingFile{
+		{name: constant.BundleFileName, content: bundlefileBytes},
+		{name: constant.LockFileName, content: m.Encoder.EncodeLockFile(b.LockFile)},
+	}
+
+	if err := m.writeFiles(workDir, files); err != nil {
 		return err
 	}
 
@@ -363,24 +401,106 @@ func (m *Manifester) Upgrade(workDir string, b *bundle.Bundle) error {
 	return nil
 }
 
-func (m *Manifester) upgradeBundleFile(workDir string, b *bundle.Bundle) error {
+func (m *Manifester) encodeBundleFile(workDir string, b *bundle.Bundle) ([]byte, error) {
 	bundlefilePath := filepath.Join(workDir, constant.BundleFileName)
-	bytes := m.Encoder.EncodeBundleFile(b.BundleFile)
 
-	if err := m.OSWrap.WriteFile(bundlefilePath, bytes, 0644); err != nil {
-		return fmt.Errorf("error occurred while '%s' file updating: %v", constant.BundleFileName, err)
+	// an existing file is edited in place to preserve user comments and formatting
+	content, err := m.OSWrap.ReadFile(bundlefilePath)
+	if err == nil {
+		return editBundleFile(content, b.BundleFile)
 	}
 
-	return nil
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error occurred while '%s' file reading: %v", constant.BundleFileName, err)
+	}
+
+	return m.Encoder.EncodeBundleFile(b.BundleFile), nil
+}
+
+type pendingFile struct {
+	name     string
+	content  []byte
+	previous []byte // content of the replaced file, nil if the file did not exist
+	tmpPath  string
 }
 
-func (m *Manifester) upgradeLockFile(workDir string, b *bundle.Bundle) error {
-	bundlefilePath := filepath.Join(workDir, constant.LockFileName)
-	bytes := m.Encoder.EncodeLockFile(b.LockFile)
+// writeFiles replaces the files in the directory. All files are written into
+// temporary files before any of them is renamed, and if one of the renames
+// fails, the files that have already been replaced get their previous content
+// back. Renaming and removing files, as well as creating temporary files with
+// unique names, are not provided by the OS wrapper, so that these operations
+// are called directly.
+func (m *Manifester) writeFiles(dir string, files []*pendingFile) (err error) {
+	defer func() {
+		for _, f := range files {
+			if f.tmpPath != "" {
+				os.Remove(f.tmpPath)
+			}
+		}
+	}()
+
+	for _, f := range files {
+		f.previous, err = m.OSWrap.ReadFile(filepath.Join(dir, f.name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error occurred while '%s' file reading: %v", f.name, err)
+		}
+
+		tmpPath, err := writeTempFile(dir, f.name, f.content)
+		if err != nil {
+			return fmt.Errorf("error occurred while '%s' file updating: %v", f.name, err)
+		}
+
+		f.tmpPath = tmpPath
+	}
+
+	for i, f := range files {
+		if err := os.Rename(f.tmpPath, filepath.Join(dir, f.name)); err != nil {
+			msg := fmt.Sprintf("error occurred while '%s' file updating: %v", f.name, err)
+			for _, replaced := range files[:i] {
+				if err := m.restoreFile(dir, replaced); err != nil {
+					msg += fmt.Sprintf("\n\t> failed to restore '%s' file: %v", replaced.name, err)
+				}
+			}
 
-	if err := m.OSWrap.WriteFile(bundlefilePath, bytes, 0644); err != nil {
-		return fmt.Errorf("error occurred while '%s' file updating: %v", constant.LockFileName, err)
+			return errors.New(msg)
+		}
+
+		f.tmpPath = ""
 	}
 
 	return nil
 }
+
+// writeTempFile writes the content into a new temporary file next to the
+// file with the name, so that concurrent writers never share the same file
+func writeTempFile(dir string, name string, content []byte) (string, error) {
+	tmp, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+func (m *Manifester) restoreFile(dir string, f *pendingFile) error {
+	path := filepath.Join(dir, f.name)
+	if f.previous == nil {
+		return os.Remove(path)
+	}
+
+	return m.OSWrap.WriteFile(path, f.previous, 0644)
+}
@@ -0,0 +1,105 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/4rchr4y/godevkit/v3/syswrap"
+	"github.com/stretchr/testify/require"
+)
+
+// hidingOSWrap reports the file with the given name as missing, so that
+// it is expected to be created, while renaming over it fails
+type hidingOSWrap struct {
+	syswrap.OSWrap
+	name string
+}
+
+func (w *hidingOSWrap) ReadFile(name string) ([]byte, error) {
+	if filepath.Base(name) == w.name {
+		return nil, fs.ErrNotExist
+	}
+
+	return w.OSWrap.ReadFile(name)
+}
+
+func TestWriteFiles(t *testing.T) {
+	t.Run("Replaced files should be restored if a later rename fails", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("old a"), 0644))
+
+		// a non-empty directory cannot be replaced with a file
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "c", "d"), 0755))
+
+		m := &Manifester{OSWrap: &hidingOSWrap{name: "c"}}
+		err := m.writeFiles(dir, []*pendingFile{
+			{name: "a", content: []byte("new a")},
+			{name: "b", content: []byte("new b")},
+			{name: "c", content: []byte("new c")},
+		})
+		require.ErrorContains(t, err, "error occurred while 'c' file updating")
+
+		content, err := os.ReadFile(filepath.Join(dir, "a"))
+		require.NoError(t, err)
+		require.Equal(t, "old a", string(content))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		require.Equal(t, []string{"a", "c"}, names, "new files and temporary files should be removed")
+	})
+
+	t.Run("Temporary files of other writers should be left as is", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".a.tmp"), []byte("other a"), 0644))
+
+		m := &Manifester{OSWrap: new(syswrap.OSWrap)}
+		require.NoError(t, m.writeFiles(dir, []*pendingFile{{name: "a", content: []byte("new a")}}))
+
+		content, err := os.ReadFile(filepath.Join(dir, ".a.tmp"))
+		require.NoError(t, err)
+		require.Equal(t, "other a", string(content))
+
+		content, err = os.ReadFile(filepath.Join(dir, "a"))
+		require.NoError(t, err)
+		require.Equal(t, "new a", string(content))
+	})
+}
+
+func TestInsertRequirement(t *testing.T) {
+	t.Run("Unchanged constraint should be reported as already installed", func(t *testing.T) {
+		dep := testBundle("github.com/x/dep", "dep")
+		parent := testBundle("github.com/x/parent", "parent")
+		parent.BundleFile.Require.List = []*bundlefile.RequirementDecl{
+			{Source: dep.Source, Name: dep.Name(), Version: ">=1.0,<2.0"},
+		}
+
+		var output bytes.Buffer
+		m := &Manifester{
+			IO:       iostream.NewIOStream(iostream.WithOutput(&output), iostream.WithErrOutput(io.Discard)),
+			Storage:  fakeStorage{},
+			Fetcher:  &fakeLister{calls: make(map[string]int)},
+			Resolver: &fakeResolver{bundles: map[string]*bundle.Bundle{dep.Source: dep}},
+		}
+
+		v, err := bundle.ParseVersionExpr(">= 1.0, < 2.0")
+		require.NoError(t, err)
+
+		err = m.InsertRequirement(context.Background(), &InsertRequirementInput{Parent: parent, Source: dep.Source, Version: v})
+		require.NoError(t, err, "the requirement should not be fetched again")
+		require.Contains(t, output.String(), "bundle github.com/x/dep@>=1.0,<2.0 is already installed")
+		require.NotContains(t, output.String(), "upgrading")
+		require.Equal(t, ">=1.0,<2.0", parent.BundleFile.Require.List[0].Version)
+	})
+}
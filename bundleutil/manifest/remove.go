@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/regofile"
+)
+
+// ImportRef points to an import declaration of a rego module
+type ImportRef struct {
+	File string // rego file path
+	Line int    // line of the import declaration
+	Path string // import path without the `data.` prefix
+}
+
+func (r *ImportRef) String() string {
+	return fmt.Sprintf("%s:%d: import %s%s", r.File, r.Line, regofile.ImportPathPrefix, r.Path)
+}
+
+// FindImports returns the imports of the bundle modules whose path matches
+// the filter, sorted by the file and the line. Imports of the bundle's own
+// files are skipped, even if they share the name with a requirement.
+func FindImports(b *bundle.Bundle, match func(importPath string) bool) []*ImportRef {
+	result := make([]*ImportRef, 0)
+	for filePath, f := range b.RegoFiles {
+		for _, v := range f.Parsed.Imports {
+			importPath := strings.TrimPrefix(v.Path.String(), regofile.ImportPathPrefix)
+			if !match(importPath) {
+				continue
+			}
+
+			if _, isLocal := b.RegoFiles[importFilePath(importPath)]; isLocal {
+				continue
+			}
+
+			result = append(result, &ImportRef{
+				File: filePath,
+				Line: v.Location.Row,
+				Path: importPath,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+
+		return result[i].Line < result[j].Line
+	})
+
+	return result
+}
+
+// ImportsOfBundle matches the imports of any module of the bundle with the name
+func ImportsOfBundle(name string) func(importPath string) bool {
+	return func(importPath string) bool {
+		return importPath == name || strings.HasPrefix(importPath, name+".")
+	}
+}
+
+type RemoveRequirementInput struct {
+	Parent *bundle.Bundle
+	Source string
+	Force  bool // remove the requirement even if its modules are still imported
+}
+
+// RemoveRequirement removes the direct requirement from the bundle file and
+// synchronizes the lock file, so that the requirements which are no longer
+// used by any other bundle are dropped as well.
+func (m *Manifester) RemoveRequirement(ctx context.Context, input *RemoveRequirementInput) error {
+	decl, idx, ok := input.Parent.BundleFile.FindIndexOfRequirement(
+		bundlefile.FilterBySource(input.Source),
+	)
+	if !ok {
+		return fmt.Errorf("bundle %s is not required by %s", input.Source, input.Parent.Repository())
+	}
+
+	if refs := FindImports(input.Parent, ImportsOfBundle(decl.Name)); len(refs) > 0 {
+		diagnostics := make([]string, len(refs))
+		for i := range refs {
+			diagnostics[i] = refs[i].String()
+		}
+
+		if !input.Force {
+			return fmt.Errorf("bundle %s is still imported:\n\t> %s\n\t> remove the imports or use '--force'",
+				input.Source, strings.Join(diagnostics, "\n\t> "),
+			)
+		}
+
+		m.IO.PrintfWarn("bundle %s is still imported:\n\t> %s", input.Source, strings.Join(diagnostics, "\n\t> "))
+	}
+
+	list := input.Parent.BundleFile.Require.List
+	input.Parent.BundleFile.Require.List = append(list[:idx:idx], list[idx+1:]...)
+
+	return m.syncLockfile(ctx, input.Parent, map[string]struct{}{decl.Name: {}})
+}
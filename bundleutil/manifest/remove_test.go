@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundle/regofile"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/4rchr4y/bpm/pkg/resolver"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) Store(b *bundle.Bundle) error            { return nil }
+func (fakeStorage) StoreSome(b *bundle.Bundle) error        { return nil }
+func (fakeStorage) Some(source string, version string) bool { return true }
+func (fakeStorage) Load(source string, version *bundle.VersionSpec) (*bundle.Bundle, error) {
+	return nil, nil
+}
+
+// fakeResolver selects the registered bundles of the declared requirements
+// as direct ones, and the registered requirements of them as indirect ones
+type fakeResolver struct {
+	bundles  map[string]*bundle.Bundle
+	requires map[string][]*bundle.Bundle
+}
+
+func (r *fakeResolver) Resolve(ctx context.Context, root *bundle.Bundle) (*resolver.Resolution, error) {
+	resolution := new(resolver.Resolution)
+	for _, decl := range root.BundleFile.Require.List {
+		resolution.Direct = append(resolution.Direct, r.bundles[decl.Source])
+		resolution.Indirect = append(resolution.Indirect, r.requires[decl.Source]...)
+	}
+
+	return resolution, nil
+}
+
+func testBundle(source, name string) *bundle.Bundle {
+	v, err := bundle.ParseVersionExpr("v1.0.0")
+	if err != nil {
+		panic(err)
+	}
+
+	return &bundle.Bundle{
+		Source:  source,
+		Version: v,
+		BundleFile: bundlefile.PrepareSchema(&bundlefile.Schema{
+			Package: &bundlefile.PackageBlock{Name: name, Repository: source},
+		}),
+		LockFile: lockfile.PrepareSchema(nil),
+	}
+}
+
+func testRegoFile(path, content string) *regofile.File {
+	return &regofile.File{Path: path, Raw: []byte(content), Parsed: ast.MustParseModule(content)}
+}
+
+func TestRemoveRequirement(t *testing.T) {
+	setup := func(files ...*regofile.File) (*Manifester, *bundle.Bundle) {
+		dep := testBundle("github.com/x/dep", "dep")
+		dep.LockFile.Consist.List = append(dep.LockFile.Consist.List, &lockfile.ModuleDecl{Package: "dep.lib"})
+		sub := testBundle("github.com/x/sub", "sub")
+		other := testBundle("github.com/x/other", "other")
+
+		parent := testBundle("github.com/x/parent", "parent")
+		parent.RegoFiles = make(map[string]*regofile.File, len(files))
+		for _, f := range files {
+			parent.RegoFiles[f.Path] = f
+		}
+
+		parent.BundleFile.Require.List = []*bundlefile.RequirementDecl{
+			NewBundlefileRequirementDecl(dep),
+			NewBundlefileRequirementDecl(other),
+		}
+
+		m := &Manifester{
+			IO:      iostream.NewIOStream(iostream.WithOutput(io.Discard), iostream.WithErrOutput(io.Discard)),
+			Storage: fakeStorage{},
+			Resolver: &fakeResolver{
+				bundles: map[string]*bundle.Bundle{
+					dep.Source:   dep,
+					other.Source: other,
+				},
+				requires: map[string][]*bundle.Bundle{
+					dep.Source: {sub},
+				},
+			},
+		}
+
+		require.NoError(t, m.SyncLockfile(context.Background(), parent))
+		require.Len(t, parent.LockFile.Require.List, 3)
+
+		return m, parent
+	}
+
+	lockedSources := func(b *bundle.Bundle) []string {
+		result := make([]string, 0, len(b.LockFile.Require.List))
+		for _, r := range b.LockFile.Require.List {
+			result = append(result, r.Source)
+		}
+
+		return result
+	}
+
+	const importingFile = "package parent.rules\n\nimport data.dep.lib\n\nallow := lib.allow\n"
+
+	t.Run("Requirement should be removed together with its indirect requirements", func(t *testing.T) {
+		m, parent := setup()
+
+		err := m.RemoveRequirement(context.Background(), &RemoveRequirementInput{Parent: parent, Source: "github.com/x/dep"})
+		require.NoError(t, err)
+		require.Len(t, parent.BundleFile.Require.List, 1)
+		require.Equal(t, "github.com/x/other", parent.BundleFile.Require.List[0].Source)
+		require.Equal(t, []string{"github.com/x/other"}, lockedSources(parent))
+	})
+
+	t.Run("Imported requirement should be refused with the import locations", func(t *testing.T) {
+		m, parent := setup(testRegoFile("rules/main.rego", importingFile))
+
+		err := m.RemoveRequirement(context.Background(), &RemoveRequirementInput{Parent: parent, Source: "github.com/x/dep"})
+		require.ErrorContains(t, err, "bundle github.com/x/dep is still imported")
+		require.ErrorContains(t, err, "rules/main.rego:3: import data.dep.lib")
+		require.ErrorContains(t, err, "'--force'")
+		require.Len(t, parent.BundleFile.Require.List, 2, "bundle file should be left as is")
+		require.Len(t, parent.LockFile.Require.List, 3, "lock file should be left as is")
+	})
+
+	t.Run("Imported requirement should be removed with force", func(t *testing.T) {
+		m, parent := setup(testRegoFile("rules/main.rego", importingFile))
+
+		err := m.RemoveRequirement(context.Background(), &RemoveRequirementInput{Parent: parent, Source: "github.com/x/dep", Force: true})
+		require.NoError(t, err)
+		require.Len(t, parent.BundleFile.Require.List, 1)
+		require.Equal(t, []string{"github.com/x/other"}, lockedSources(parent))
+	})
+
+	t.Run("Imports of local files sharing the requirement name should be ignored", func(t *testing.T) {
+		m, parent := setup(
+			testRegoFile("rules/main.rego", importingFile),
+			testRegoFile("dep/lib.rego", "package dep.lib\n\nallow := true\n"),
+		)
+
+		err := m.RemoveRequirement(context.Background(), &RemoveRequirementInput{Parent: parent, Source: "github.com/x/dep"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"github.com/x/other"}, lockedSources(parent))
+	})
+}
@@ -0,0 +1,216 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/hashicorp/go-version"
+)
+
+// UpdateLimit restricts how far a requirement can be updated
+type UpdateLimit int
+
+const (
+	UpdateLatest UpdateLimit = iota // any newer version
+	UpdateMinor                     // newer versions with the same major version
+	UpdatePatch                     // newer versions with the same major and minor version
+)
+
+// RequirementUpdate describes a direct requirement that has a newer version
+type RequirementUpdate struct {
+	Source string              // requirement source as declared in the bundle file
+	Name   string              // bundle name
+	Old    *bundle.VersionSpec // currently locked version
+	New    *bundle.VersionSpec // newest allowed version
+}
+
+type FindUpdatesInput struct {
+	Parent  *bundle.Bundle
+	Sources []string // sources of the requirements to update, all direct requirements if empty
+	Limit   UpdateLimit
+}
+
+// FindUpdates looks for the newest allowed version of each direct requirement.
+// Only the tags of the requirement repositories are listed, nothing is fetched.
+// Requirements declared as constraints are only updated within the constraint,
+// requirements that are already up to date are not included in the result.
+func (m *Manifester) FindUpdates(ctx context.Context, input *FindUpdatesInput) ([]*RequirementUpdate, error) {
+	decls, err := selectRequirements(input.Parent, input.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*RequirementUpdate, 0, len(decls))
+	for _, decl := range decls {
+		declared, err := bundle.ParseVersionExpr(decl.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version '%s' of %s: %v", decl.Version, decl.Source, err)
+		}
+
+		current := lockedDirectVersion(input.Parent.LockFile, decl.Source, declared)
+		if current == nil || current.IsPseudo() {
+			m.IO.PrintfWarn("bundle %s has no semantic version, skipping", decl.Source)
+			continue
+		}
+
+		c, err := updateConstraint(current, declared, input.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		versions, err := m.Fetcher.ListVersions(ctx, decl.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		newest := newestAllowedVersion(versions, c)
+		if newest == nil || !newest.GreaterThan(current.SemTag) {
+			continue
+		}
+
+		v, err := bundle.ParseVersionExpr(newest.Original())
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &RequirementUpdate{
+			Source: decl.Source,
+			Name:   decl.Name,
+			Old:    current,
+			New:    v,
+		})
+	}
+
+	return result, nil
+}
+
+// ApplyUpdates records the new versions and synchronizes the lock file.
+// The bundle file keeps requirements declared as constraints as is, only
+// the version locked for them is changed.
+func (m *Manifester) ApplyUpdates(ctx context.Context, parent *bundle.Bundle, updates []*RequirementUpdate) error {
+	for _, u := range updates {
+		decl, _, ok := parent.BundleFile.FindIndexOfRequirement(bundlefile.FilterBySource(u.Source))
+		if !ok {
+			return fmt.Errorf("bundle %s is not required by %s", u.Source, parent.Repository())
+		}
+
+		m.IO.PrintfInfo("upgrading %s => %s",
+			bundleutil.FormatSourceWithVersion(u.Source, u.Old.String()),
+			bundleutil.FormatSourceWithVersion(u.Source, u.New.String()),
+		)
+
+		declared, err := bundle.ParseVersionExpr(decl.Version)
+		if err != nil {
+			return err
+		}
+
+		if !declared.IsConstraint() {
+			decl.Version = u.New.String()
+			continue
+		}
+
+		// the resolver prefers the locked version as long as it satisfies
+		// the constraint, so the lock file is pointed to the new version
+		locked, _, ok := parent.LockFile.FindIndexOfRequirement(
+			lockfile.RequireFilterBySource(remote.Redact(u.Source)),
+			lockfile.RequireFilterByDirection(lockfile.Direct),
+		)
+		if ok {
+			locked.Version = u.New.String()
+		}
+	}
+
+	return m.SyncLockfile(ctx, parent)
+}
+
+// selectRequirements returns the direct requirements with the given
+// sources in the same order, or all of them if no source is given
+func selectRequirements(parent *bundle.Bundle, sources []string) ([]*bundlefile.RequirementDecl, error) {
+	if parent.BundleFile.Require == nil {
+		if len(sources) > 0 {
+			return nil, fmt.Errorf("bundle %s is not required by %s", sources[0], parent.Repository())
+		}
+
+		return nil, nil
+	}
+
+	if len(sources) == 0 {
+		return parent.BundleFile.Require.List, nil
+	}
+
+	result := make([]*bundlefile.RequirementDecl, 0, len(sources))
+	for _, source := range sources {
+		decl, _, ok := parent.BundleFile.FindIndexOfRequirement(bundlefile.FilterBySource(source))
+		if !ok {
+			return nil, fmt.Errorf("bundle %s is not required by %s", source, parent.Repository())
+		}
+
+		result = append(result, decl)
+	}
+
+	return result, nil
+}
+
+// lockedDirectVersion returns the version locked for the direct requirement,
+// or the declared version if the requirement is not locked yet
+func lockedDirectVersion(lockFile *lockfile.Schema, source string, declared *bundle.VersionSpec) *bundle.VersionSpec {
+	if lockFile != nil {
+		locked, _, ok := lockFile.FindIndexOfRequirement(
+			lockfile.RequireFilterBySource(remote.Redact(source)),
+			lockfile.RequireFilterByDirection(lockfile.Direct),
+		)
+		if ok {
+			if v, err := bundle.ParseVersionExpr(locked.Version); err == nil && v != nil && !v.IsConstraint() {
+				return v
+			}
+		}
+	}
+
+	if declared == nil || declared.IsConstraint() {
+		return nil
+	}
+
+	return declared
+}
+
+// newestAllowedVersion returns the highest version that satisfies
+// the constraint, pre-releases are skipped
+func newestAllowedVersion(versions []*version.Version, c *bundle.VersionConstraint) *version.Version {
+	var result *version.Version
+	for _, v := range versions {
+		if v.Prerelease() != "" || !c.Check(v) {
+			continue
+		}
+
+		if result == nil || v.GreaterThan(result) {
+			result = v
+		}
+	}
+
+	return result
+}
+
+// updateConstraint builds the constraint that matches the current
+// version and all newer versions allowed by the limit and the declaration
+func updateConstraint(current, declared *bundle.VersionSpec, limit UpdateLimit) (*bundle.VersionConstraint, error) {
+	exprs := []string{">= " + current.SemTag.String()}
+
+	switch limit {
+	case UpdateMinor:
+		exprs = append(exprs, fmt.Sprintf("< %d.0.0", current.Major()+1))
+	case UpdatePatch:
+		exprs = append(exprs, fmt.Sprintf("< %d.%d.0", current.Major(), current.Minor()+1))
+	}
+
+	if declared.IsConstraint() {
+		exprs = append(exprs, declared.Constraint.Expr)
+	}
+
+	return bundle.ParseVersionConstraint(strings.Join(exprs, ", "))
+}
@@ -0,0 +1,165 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLister struct {
+	tags  map[string][]string
+	calls map[string]int
+}
+
+func (l *fakeLister) Fetch(ctx context.Context, source string, version *bundle.VersionSpec) (*fetch.FetchOutput, error) {
+	return nil, fmt.Errorf("fetching is not expected")
+}
+
+func (l *fakeLister) ListVersions(ctx context.Context, source string) ([]*version.Version, error) {
+	l.calls[source]++
+
+	result := make([]*version.Version, 0, len(l.tags[source]))
+	for _, tag := range l.tags[source] {
+		result = append(result, version.Must(version.NewVersion(tag)))
+	}
+
+	return result, nil
+}
+
+// testParent creates a bundle with a single direct requirement,
+// the declared version is also locked as is
+func testParent(source, version string) *bundle.Bundle {
+	bundleFile := testSchema(&bundlefile.RequirementDecl{Source: source, Name: source, Version: version})
+	bundleFile.Package = &bundlefile.PackageBlock{Name: "example", Repository: "github.com/example/policies"}
+
+	lockFile := lockfile.PrepareSchema(nil)
+	lockFile.Require.List = append(lockFile.Require.List, &lockfile.RequirementDecl{
+		Source:    source,
+		Name:      source,
+		Version:   version,
+		Direction: lockfile.Direct.String(),
+	})
+
+	return &bundle.Bundle{BundleFile: bundleFile, LockFile: lockFile}
+}
+
+func TestFindUpdates(t *testing.T) {
+	newManifester := func() (*Manifester, *fakeLister) {
+		lister := &fakeLister{
+			tags: map[string][]string{
+				"github.com/example/base": {"v2.0.0", "v1.2.3", "v1.2.9", "v1.3.0", "v1.4.0-rc.1"},
+			},
+			calls: make(map[string]int),
+		}
+
+		return &Manifester{
+			IO:      iostream.NewIOStream(iostream.WithOutput(io.Discard), iostream.WithErrOutput(io.Discard)),
+			Fetcher: lister,
+		}, lister
+	}
+
+	tests := []struct {
+		name     string
+		limit    UpdateLimit
+		expected string
+	}{
+		{name: "Latest", limit: UpdateLatest, expected: "v2.0.0"},
+		{name: "Minor", limit: UpdateMinor, expected: "v1.3.0"},
+		{name: "Patch", limit: UpdatePatch, expected: "v1.2.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" limit should select the newest allowed tag", func(t *testing.T) {
+			m, _ := newManifester()
+
+			updates, err := m.FindUpdates(context.Background(), &FindUpdatesInput{
+				Parent: testParent("github.com/example/base", "v1.2.3"),
+				Limit:  tt.limit,
+			})
+			require.NoError(t, err)
+			require.Len(t, updates, 1)
+			require.Equal(t, "v1.2.3", updates[0].Old.String())
+			require.Equal(t, tt.expected, updates[0].New.String())
+		})
+	}
+
+	t.Run("Pre-release should not be selected", func(t *testing.T) {
+		m, _ := newManifester()
+
+		updates, err := m.FindUpdates(context.Background(), &FindUpdatesInput{
+			Parent: testParent("github.com/example/base", "v1.3.0"),
+			Limit:  UpdateMinor,
+		})
+		require.NoError(t, err)
+		require.Empty(t, updates)
+	})
+
+	t.Run("Unknown source should be reported", func(t *testing.T) {
+		m, lister := newManifester()
+
+		_, err := m.FindUpdates(context.Background(), &FindUpdatesInput{
+			Parent:  testParent("github.com/example/base", "v1.2.3"),
+			Sources: []string{"github.com/example/unknown"},
+		})
+		require.ErrorContains(t, err, "bundle github.com/example/unknown is not required")
+		require.Empty(t, lister.calls)
+	})
+
+	t.Run("Pseudo version should be skipped", func(t *testing.T) {
+		m, lister := newManifester()
+
+		updates, err := m.FindUpdates(context.Background(), &FindUpdatesInput{
+			Parent: testParent("github.com/example/base", "v0.0.0+20240128102927-ab4647768668"),
+		})
+		require.NoError(t, err)
+		require.Empty(t, updates)
+		require.Empty(t, lister.calls, "versions of untagged commits should not be listed")
+	})
+}
+
+func TestUpdateConstraint(t *testing.T) {
+	tags := []string{"v1.2.3", "v1.2.9", "v1.3.0", "v1.9.0", "v2.0.0"}
+
+	tests := []struct {
+		name     string
+		declared string
+		limit    UpdateLimit
+		expected []string
+	}{
+		{name: "latest", declared: "v1.2.3", limit: UpdateLatest, expected: tags},
+		{name: "minor", declared: "v1.2.3", limit: UpdateMinor, expected: []string{"v1.2.3", "v1.2.9", "v1.3.0", "v1.9.0"}},
+		{name: "patch", declared: "v1.2.3", limit: UpdatePatch, expected: []string{"v1.2.3", "v1.2.9"}},
+		{name: "declared constraint", declared: "< 1.5", limit: UpdateLatest, expected: []string{"v1.2.3", "v1.2.9", "v1.3.0"}},
+	}
+
+	current, err := bundle.ParseVersionExpr("v1.2.3")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			declared, err := bundle.ParseVersionExpr(tt.declared)
+			require.NoError(t, err)
+
+			c, err := updateConstraint(current, declared, tt.limit)
+			require.NoError(t, err)
+
+			matched := make([]string, 0)
+			for _, tag := range tags {
+				if c.Check(version.Must(version.NewVersion(tag))) {
+					matched = append(matched, tag)
+				}
+			}
+
+			require.Equal(t, tt.expected, matched)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/pkg/linker"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
+	opabundle "github.com/open-policy-agent/opa/bundle"
+	"github.com/spf13/cobra"
+)
+
+const cmdBuildDesc = `
+The 'bpm build' command builds the bundle located at PATH (the current
+directory by default) together with all of its requirements into a
+standard OPA bundle, that can be loaded with 'opa run -b' or served
+to OPA by a bundle server.
+
+Every bpm bundle becomes one of the OPA bundle roots, named after the
+bundle. Its rego files are placed under the directory with the same
+name, with bundle imports rewritten to regular 'data.' imports, and its
+'data.json' and 'data.yaml' files are loaded as documents under the
+same root. All other files are not included.
+`
+
+func NewCmdBuild(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "build [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "Build an OPA bundle from a bundle and its requirements",
+		Long:  cmdBuildDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return buildRun(cmd.Context(), &buildOptions{
+				io:      f.IOStream,
+				dir:     dir,
+				output:  output,
+				osWrap:  f.OS,
+				storage: f.Storage,
+				linker:  f.Linker,
+			})
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "bundle.tar.gz", "OPA bundle file path")
+	return cmd
+}
+
+type buildOptions struct {
+	io      core.IO
+	dir     string // bundle directory
+	output  string // OPA bundle file path
+	osWrap  osiface.OSWrapper
+	storage *storage.Storage
+	linker  *linker.Linker
+}
+
+func buildRun(ctx context.Context, opts *buildOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	linked, err := opts.linker.LinkBundles(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	result, err := linker.Build(linked)
+	if err != nil {
+		return err
+	}
+
+	if _, err := linker.Compile(result); err != nil {
+		return fmt.Errorf("failed to compile %s: %v", b.Repository(), err)
+	}
+
+	var buf bytes.Buffer
+	if err := opabundle.NewWriter(&buf).UseModulePath(true).Write(*result); err != nil {
+		return fmt.Errorf("failed to build %s: %v", b.Repository(), err)
+	}
+
+	if err := opts.osWrap.WriteFile(opts.output, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	opts.io.PrintfOk("bundle %s has been built to %s", b.Repository(), filepath.Clean(opts.output))
+	opts.io.Printf("roots: %s\nrevision: %s\n", strings.Join(*result.Manifest.Roots, ", "), result.Manifest.Revision)
+
+	return nil
+}
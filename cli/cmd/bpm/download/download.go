@@ -2,25 +2,49 @@ package download
 
 import (
 	"context"
+	"fmt"
+	"os"
 
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundleutil"
 	"github.com/4rchr4y/bpm/bundleutil/inspect"
 	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
 	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/core"
 	"github.com/4rchr4y/bpm/fetch"
 	"github.com/4rchr4y/bpm/storage"
 	"github.com/spf13/cobra"
 )
 
+const cmdDownloadDesc = `
+The 'bpm download' command reads the 'lockfile.hcl' of the current bundle
+and downloads every required bundle at its exact locked version.
+
+Each downloaded bundle is checked against the 'h1' and 'h2' checksums
+recorded in the lock file before it is saved to the local storage ($BPM_PATH).
+The 'bundle.hcl' file is never modified by this command.
+`
+
 func NewCmdDownload(f *factory.Factory) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "download",
 		Aliases: []string{"d"},
 		Args:    require.NoArgs,
 		Short:   "Download all bundle requirements",
+		Long:    cmdDownloadDesc,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
 			return downloadRun(cmd.Context(), &downloadOptions{
-				storage: f.Storage,
-				fetcher: f.Fetcher,
+				io:        f.IOStream,
+				workDir:   wd,
+				storage:   f.Storage,
+				fetcher:   f.Fetcher,
+				inspector: f.Inspector,
 			})
 		},
 	}
@@ -29,13 +53,15 @@ func NewCmdDownload(f *factory.Factory) *cobra.Command {
 }
 
 type downloadOptions struct {
+	io        core.IO
+	workDir   string // bundle working directory
 	storage   *storage.Storage
 	fetcher   *fetch.Fetcher
 	inspector *inspect.Inspector
 }
 
 func downloadRun(ctx context.Context, opts *downloadOptions) error {
-	b, err := opts.storage.LoadFromAbs(".", nil)
+	b, err := opts.storage.LoadFromAbs(opts.workDir, nil)
 	if err != nil {
 		return err
 	}
@@ -44,9 +70,32 @@ func downloadRun(ctx context.Context, opts *downloadOptions) error {
 		return err
 	}
 
-	// if err := opts.Saver.SaveToDisk(b); err != nil {
-	// 	return err
-	// }
+	for _, r := range b.LockFile.Require.List {
+		v, err := bundle.ParseVersionExpr(r.Version)
+		if err != nil {
+			return err
+		}
+
+		if v == nil {
+			return fmt.Errorf("requirement %s has no locked version", r.Source)
+		}
+
+		formatted := bundleutil.FormatSourceWithVersion(r.Source, r.Version)
+		required, err := opts.fetcher.PlainFetch(ctx, r.Source, v)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %v", formatted, err)
+		}
+
+		if !opts.inspector.Report(formatted, opts.inspector.CheckRequirement(required, r)) {
+			return fmt.Errorf("%s: downloaded bundle does not match %s", formatted, constant.LockFileName)
+		}
+
+		if err := opts.storage.StoreSome(required); err != nil {
+			return err
+		}
+
+		opts.io.PrintfOk("bundle %s", formatted)
+	}
 
 	return nil
 }
@@ -0,0 +1,326 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/cli/cmdutil/table"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/pkg/linker"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/lineage"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spf13/cobra"
+)
+
+const cmdEvalDesc = `
+The 'bpm eval' command evaluates the QUERY against the bundle located in
+the current directory (or the one set with '--bundle'), linked together
+with all of its requirements, the same way as 'bpm build' does it.
+
+Documents of the bundles' 'data.json' and 'data.yaml' files are available
+under their roots. Files passed with '--data' are merged into the data on
+top of them, so their values take precedence. The input document is read
+from the '--input' file, use '-' to read it from stdin.
+
+With '--explain' the evaluation trace is printed after the result, or
+included into the 'explanation' field of the result in the JSON format:
+'notes' shows the 'trace' calls, 'fails' shows the failed expressions,
+and 'full' shows every step of the evaluation.
+`
+
+const (
+	formatJSON   = "json"
+	formatPretty = "pretty"
+)
+
+var explainModes = map[string]func([]*topdown.Event) []*topdown.Event{
+	"notes": lineage.Notes,
+	"fails": lineage.Fails,
+	"full":  lineage.Full,
+}
+
+func NewCmdEval(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval QUERY",
+		Args:  require.ExactArgs(1),
+		Short: "Evaluate a query against the linked bundle",
+		Long:  cmdEvalDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cmd.Flags().GetString("bundle")
+			if err != nil {
+				return err
+			}
+
+			input, err := cmd.Flags().GetString("input")
+			if err != nil {
+				return err
+			}
+
+			data, err := cmd.Flags().GetStringArray("data")
+			if err != nil {
+				return err
+			}
+
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+
+			explain, err := cmd.Flags().GetString("explain")
+			if err != nil {
+				return err
+			}
+
+			if format != formatJSON && format != formatPretty {
+				return fmt.Errorf("unknown output format '%s', expected '%s' or '%s'", format, formatJSON, formatPretty)
+			}
+
+			if _, exists := explainModes[explain]; explain != "" && !exists {
+				return fmt.Errorf("unknown explain mode '%s', expected 'notes', 'fails' or 'full'", explain)
+			}
+
+			return evalRun(cmd.Context(), &evalOptions{
+				io:      f.IOStream,
+				dir:     dir,
+				query:   args[0],
+				input:   input,
+				data:    data,
+				format:  format,
+				explain: explain,
+				osWrap:  f.OS,
+				storage: f.Storage,
+				linker:  f.Linker,
+			})
+		},
+	}
+
+	cmd.Flags().StringP("bundle", "b", ".", "Bundle directory")
+	cmd.Flags().StringP("input", "i", "", "Input document file, '-' to read from stdin")
+	cmd.Flags().StringArrayP("data", "d", nil, "Data document file overriding the bundle data (can be repeated)")
+	cmd.Flags().StringP("format", "f", formatJSON, "Output format, 'json' or 'pretty'")
+	cmd.Flags().String("explain", "", "Print the evaluation trace, 'notes', 'fails' or 'full'")
+	return cmd
+}
+
+type evalOptions struct {
+	io      core.IO
+	dir     string   // bundle directory
+	query   string   // query to evaluate
+	input   string   // input document file
+	data    []string // data document files
+	format  string   // output format
+	explain string   // explain mode
+	osWrap  osiface.OSWrapper
+	storage *storage.Storage
+	linker  *linker.Linker
+}
+
+func evalRun(ctx context.Context, opts *evalOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	linked, err := opts.linker.LinkBundles(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	result, err := linker.Build(linked)
+	if err != nil {
+		return err
+	}
+
+	compiler, err := linker.Compile(result)
+	if err != nil {
+		return fmt.Errorf("failed to compile %s: %v", b.Repository(), err)
+	}
+
+	for _, filePath := range opts.data {
+		doc, err := readDocument(opts, filePath)
+		if err != nil {
+			return err
+		}
+
+		override, ok := doc.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("data file %s must contain an object", filePath)
+		}
+
+		mergeData(result.Data, override)
+	}
+
+	tracer := topdown.NewBufferTracer()
+	options := []func(*rego.Rego){
+		rego.Query(opts.query),
+		rego.Compiler(compiler),
+		rego.Store(inmem.NewFromObject(result.Data)),
+		rego.EnablePrintStatements(true),
+		rego.PrintHook(topdown.NewPrintHook(opts.io.GetStdoutErr())),
+	}
+
+	if opts.input != "" {
+		input, err := readDocument(opts, opts.input)
+		if err != nil {
+			return err
+		}
+
+		options = append(options, rego.Input(input))
+	}
+
+	if opts.explain != "" {
+		options = append(options, rego.QueryTracer(tracer))
+	}
+
+	rs, err := rego.New(options...).Eval(ctx)
+	if err != nil {
+		return err
+	}
+
+	var explanation []string
+	if opts.explain != "" {
+		var buf bytes.Buffer
+		topdown.PrettyTraceWithLocation(&buf, explainModes[opts.explain](*tracer))
+		explanation = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	}
+
+	return printResult(opts.io.GetStdout(), opts.format, rs, explanation)
+}
+
+// readDocument reads a JSON or YAML document from the file or stdin
+func readDocument(opts *evalOptions, filePath string) (interface{}, error) {
+	var (
+		content []byte
+		err     error
+	)
+
+	if filePath == "-" {
+		content, err = io.ReadAll(opts.io.GetStdin())
+	} else {
+		content, err = opts.osWrap.ReadFile(filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := util.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filePath, err)
+	}
+
+	return doc, nil
+}
+
+// mergeData merges the override into the data, objects are
+// merged recursively and any other values are replaced
+func mergeData(data, override map[string]interface{}) {
+	for key, value := range override {
+		existing, ok1 := data[key].(map[string]interface{})
+		valueObj, ok2 := value.(map[string]interface{})
+		if ok1 && ok2 {
+			mergeData(existing, valueObj)
+			continue
+		}
+
+		data[key] = value
+	}
+}
+
+// printResult prints the result set followed by the explanation lines,
+// in the JSON format the explanation is a part of the printed document
+func printResult(w io.Writer, format string, rs rego.ResultSet, explanation []string) error {
+	if format == formatJSON {
+		output := struct {
+			Result      rego.ResultSet `json:"result,omitempty"`
+			Explanation []string       `json:"explanation,omitempty"`
+		}{rs, explanation}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	}
+
+	if err := printValues(w, rs); err != nil {
+		return err
+	}
+
+	for _, line := range explanation {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printValues(w io.Writer, rs rego.ResultSet) error {
+	if len(rs) == 0 {
+		_, err := fmt.Fprintln(w, "undefined")
+		return err
+	}
+
+	// a single value is printed as is, without a table
+	if len(rs) == 1 && len(rs[0].Bindings) == 0 && len(rs[0].Expressions) == 1 {
+		content, err := json.MarshalIndent(rs[0].Expressions[0].Value, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(content))
+		return err
+	}
+
+	return printTable(w, rs)
+}
+
+// printTable prints the bindings of each result as a table row, or
+// the values of expressions if the query does not bind any variables
+func printTable(w io.Writer, rs rego.ResultSet) error {
+	header := make([]string, 0, len(rs[0].Bindings))
+	for name := range rs[0].Bindings {
+		header = append(header, name)
+	}
+	sort.Strings(header)
+
+	if len(header) == 0 {
+		for _, expr := range rs[0].Expressions {
+			header = append(header, expr.Text)
+		}
+	}
+
+	t := table.New(header...)
+	for _, r := range rs {
+		row := make([]string, 0, len(header))
+		for i, name := range header {
+			var value interface{}
+			if len(r.Bindings) > 0 {
+				value = r.Bindings[name]
+			} else if i < len(r.Expressions) {
+				value = r.Expressions[i].Value
+			}
+
+			content, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+
+			row = append(row, string(content))
+		}
+
+		t.Append(row...)
+	}
+
+	return t.Print(w)
+}
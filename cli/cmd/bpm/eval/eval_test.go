@@ -0,0 +1,87 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintResult(t *testing.T) {
+	single := rego.ResultSet{{
+		Expressions: []*rego.ExpressionValue{{Value: map[string]interface{}{"allow": true}, Text: "data.policy"}},
+	}}
+
+	t.Run("JSON output should embed the explanation", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, printResult(&buf, formatJSON, single, []string{"Enter data.policy", "Exit data.policy"}))
+
+		var output struct {
+			Result      []map[string]interface{} `json:"result"`
+			Explanation []string                 `json:"explanation"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &output), "output should be a single JSON document")
+		require.Len(t, output.Result, 1)
+		require.Equal(t, []string{"Enter data.policy", "Exit data.policy"}, output.Explanation)
+	})
+
+	t.Run("JSON output should omit the explanation when it is not requested", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, printResult(&buf, formatJSON, single, nil))
+		require.NotContains(t, buf.String(), "explanation")
+	})
+
+	t.Run("Pretty output should print a single value as is", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, printResult(&buf, formatPretty, single, []string{"Enter data.policy"}))
+		require.Equal(t, "{\n  \"allow\": true\n}\nEnter data.policy\n", buf.String())
+	})
+
+	t.Run("Pretty output should print undefined for an empty result", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, printResult(&buf, formatPretty, nil, nil))
+		require.Equal(t, "undefined\n", buf.String())
+	})
+
+	t.Run("Pretty output should print the bindings as a table", func(t *testing.T) {
+		rs := rego.ResultSet{
+			{Bindings: rego.Vars{"x": "a", "y": 1}},
+			{Bindings: rego.Vars{"x": "b", "y": 2}},
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, printResult(&buf, formatPretty, rs, nil))
+		require.Regexp(t, `(?m)^\W*x\W+y\W*$`, buf.String())
+		require.Regexp(t, `(?m)^\W*"a"\W+1\W*$`, buf.String())
+		require.Regexp(t, `(?m)^\W*"b"\W+2\W*$`, buf.String())
+	})
+}
+
+func TestMergeData(t *testing.T) {
+	data := map[string]interface{}{
+		"policy": map[string]interface{}{
+			"limit": 10,
+			"users": []interface{}{"alice"},
+		},
+		"other": "kept",
+	}
+
+	mergeData(data, map[string]interface{}{
+		"policy": map[string]interface{}{
+			"limit": 20,
+			"users": []interface{}{"bob"},
+			"mode":  "strict",
+		},
+	})
+
+	require.Equal(t, map[string]interface{}{
+		"policy": map[string]interface{}{
+			"limit": 20,
+			"users": []interface{}{"bob"},
+			"mode":  "strict",
+		},
+		"other": "kept",
+	}, data, "objects should be merged and other values replaced")
+}
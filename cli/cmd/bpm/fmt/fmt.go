@@ -0,0 +1,166 @@
+package formatting
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/open-policy-agent/opa/format"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+const cmdFmtDesc = `
+The 'bpm fmt' command formats the rego files and the 'bundle.hcl' of the
+bundle located at PATH (the current directory by default). Rego files are
+formatted the same way as 'opa fmt' does it, and 'bundle.hcl' gets the
+canonical HCL layout. Files ignored by '.bpmignore' are left untouched.
+
+With '--diff' the changes are printed instead of being written, and with
+'--check' the command only lists the files that are not formatted and
+exits with a non-zero code if there are any.
+`
+
+func NewCmdFmt(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fmt [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "Format rego files and the bundle file",
+		Long:  cmdFmtDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			check, err := cmd.Flags().GetBool("check")
+			if err != nil {
+				return err
+			}
+
+			diff, err := cmd.Flags().GetBool("diff")
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return fmtRun(&fmtOptions{
+				io:      f.IOStream,
+				dir:     dir,
+				check:   check,
+				diff:    diff,
+				osWrap:  f.OS,
+				storage: f.Storage,
+			})
+		},
+	}
+
+	cmd.Flags().Bool("check", false, "Only list unformatted files and fail if there are any")
+	cmd.Flags().Bool("diff", false, "Print the changes instead of writing them")
+	return cmd
+}
+
+type fmtOptions struct {
+	io      core.IO
+	dir     string // bundle directory
+	check   bool   // only report unformatted files
+	diff    bool   // print the changes
+	osWrap  osiface.OSWrapper
+	storage *storage.Storage
+}
+
+func fmtRun(opts *fmtOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string][]byte, len(b.RegoFiles)+1)
+	for filePath, f := range b.RegoFiles {
+		files[filePath] = f.Raw
+	}
+
+	files[constant.BundleFileName], err = opts.osWrap.ReadFile(filepath.Join(opts.dir, constant.BundleFileName))
+	if err != nil {
+		return err
+	}
+
+	filePaths := make([]string, 0, len(files))
+	for filePath := range files {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+
+	unformatted := make([]string, 0)
+	for _, filePath := range filePaths {
+		formatted, err := formatFile(filePath, files[filePath])
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(formatted, files[filePath]) {
+			continue
+		}
+
+		unformatted = append(unformatted, filePath)
+		fullPath := filepath.Join(opts.dir, filePath)
+
+		if opts.diff {
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(string(files[filePath])),
+				B:        difflib.SplitLines(string(formatted)),
+				FromFile: fullPath + " (original)",
+				ToFile:   fullPath + " (formatted)",
+				Context:  3,
+			})
+			if err != nil {
+				return err
+			}
+
+			opts.io.Printf("%s", diff)
+		}
+
+		if opts.check {
+			opts.io.Println(fullPath)
+			continue
+		}
+
+		if opts.diff {
+			continue
+		}
+
+		if err := opts.osWrap.WriteFile(fullPath, formatted, 0644); err != nil {
+			return err
+		}
+
+		opts.io.PrintfOk("%s has been formatted", fullPath)
+	}
+
+	if opts.check && len(unformatted) > 0 {
+		return fmt.Errorf("%d files are not formatted, run 'bpm fmt' to format them", len(unformatted))
+	}
+
+	return nil
+}
+
+func formatFile(filePath string, content []byte) ([]byte, error) {
+	if strings.HasSuffix(filePath, constant.RegoFileExt) {
+		return format.Source(filePath, content)
+	}
+
+	// hclwrite formats any input, so the syntax must be checked separately
+	if _, diags := hclwrite.ParseConfig(content, filePath, hcl.InitialPos); diags.HasErrors() {
+		return nil, diags
+	}
+
+	return hclwrite.Format(content), nil
+}
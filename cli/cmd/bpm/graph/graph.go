@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/pkg/graph"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+const cmdGraphDesc = `
+The 'bpm graph' command prints the dependency graph of the bundle located
+at PATH (the current directory by default). The graph consists of two
+parts: the requirements between bundles, and the imports between the
+modules of different bundles, labeled with the file and the line of
+the import declaration.
+
+The graph is built from the lock files, the lock files of requirements
+are taken from the local storage, so all of them must be installed.
+
+Use '--module' to only show the modules of the given package together
+with the modules they import or are imported by.
+`
+
+func NewCmdGraph(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "Print the dependency graph of a bundle",
+		Long:  cmdGraphDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+
+			if !lo.Contains(graph.Formats[:], graph.Format(format)) {
+				return fmt.Errorf("unknown graph format '%s', expected one of: %s", format, formatList())
+			}
+
+			module, err := cmd.Flags().GetString("module")
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return graphRun(&graphOptions{
+				io:      f.IOStream,
+				dir:     dir,
+				format:  graph.Format(format),
+				module:  module,
+				storage: f.Storage,
+			})
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", string(graph.FormatDOT), fmt.Sprintf("Output format: %s", formatList()))
+	cmd.Flags().StringP("module", "m", "", "Only show the modules of the package and their neighbours")
+	return cmd
+}
+
+func formatList() string {
+	return strings.Join(lo.Map(graph.Formats[:], func(f graph.Format, _ int) string { return string(f) }), ", ")
+}
+
+type graphOptions struct {
+	io      core.IO
+	dir     string       // bundle directory
+	format  graph.Format // output format
+	module  string       // package to focus on
+	storage *storage.Storage
+}
+
+func graphRun(opts *graphOptions) error {
+	g, err := graph.BuildFromStorage(opts.storage, opts.dir)
+	if err != nil {
+		return err
+	}
+
+	if opts.module != "" {
+		if g, err = g.Focus(opts.module); err != nil {
+			return err
+		}
+	}
+
+	return graph.Write(opts.io.GetStdout(), g, opts.format)
+}
@@ -2,32 +2,70 @@ package install
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundleutil"
+	"github.com/4rchr4y/bpm/bundleutil/inspect"
 	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
 	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/core"
 	"github.com/4rchr4y/bpm/fetch"
 	"github.com/4rchr4y/bpm/storage"
 	"github.com/spf13/cobra"
 )
 
+const cmdInstallDesc = `
+The 'bpm install' command downloads a bundle together with all of its
+transitive requirements and saves them to the local storage ($BPM_PATH).
+
+The version can be specified either as a suffix of the repository
+(e.g. 'github.com/4rchr4y/example@v1.0.0') or with the '--version' flag.
+
+When no repository is given, every requirement listed in the 'lockfile.hcl'
+of the current bundle is installed at its locked version. Requirements that
+are not installed yet are checked against the 'h1' and 'h2' checksums
+recorded in the lock file before they are saved.
+`
+
 func NewCmdInstall(f *factory.Factory) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "install REPOSITORY",
+		Use:     "install [REPOSITORY[@version]]",
 		Aliases: []string{"i"},
-		Args:    require.ExactArgs(1),
-		Short:   "Install a package from the specified repository",
+		Args:    require.MaximumNArgs(1),
+		Short:   "Install a bundle and its requirements into the local storage",
+		Long:    cmdInstallDesc,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			version, err := cmd.Flags().GetString("version")
 			if err != nil {
 				return err
 			}
 
-			return installRun(cmd.Context(), &installOptions{
-				URL:     args[0],
-				Version: version,
-				Fetcher: f.Fetcher,
-				Storage: f.Storage,
-			})
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			opts := &installOptions{
+				io:        f.IOStream,
+				workDir:   wd,
+				version:   version,
+				fetcher:   f.Fetcher,
+				storage:   f.Storage,
+				inspector: f.Inspector,
+			}
+
+			if len(args) > 0 {
+				opts.source, opts.version, err = splitSourceVersion(args[0], version)
+				if err != nil {
+					return err
+				}
+			}
+
+			return installRun(cmd.Context(), opts)
 		},
 	}
 
@@ -36,21 +74,114 @@ func NewCmdInstall(f *factory.Factory) *cobra.Command {
 }
 
 type installOptions struct {
-	URL     string // bundle repository that needs to be installed
-	Version string // specified bundle version
-	Fetcher *fetch.Fetcher
-	Storage *storage.Storage
+	io        core.IO
+	workDir   string // bundle working directory
+	source    string // bundle repository that needs to be installed
+	version   string // specified bundle version
+	fetcher   *fetch.Fetcher
+	storage   *storage.Storage
+	inspector *inspect.Inspector
 }
 
 func installRun(ctx context.Context, opts *installOptions) error {
-	// b, err := opts.Loader.DownloadBundle(ctx, opts.URL, opts.Version)
-	// if err != nil {
-	// 	return err
-	// }
+	if opts.source == "" {
+		return installLockfileRun(ctx, opts)
+	}
+
+	v, err := bundle.ParseVersionExpr(opts.version)
+	if err != nil {
+		return err
+	}
+
+	result, err := opts.fetcher.Fetch(ctx, opts.source, v)
+	if err != nil {
+		return err
+	}
+
+	return storeAll(opts, result.Merge())
+}
+
+// installLockfileRun installs all requirements listed
+// in the lock file of the current working bundle
+func installLockfileRun(ctx context.Context, opts *installOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.workDir, nil)
+	if err != nil {
+		return err
+	}
 
-	// if err := opts.Saver.SaveToDisk(b); err != nil {
-	// 	return err
-	// }
+	if len(b.LockFile.Require.List) == 0 {
+		opts.io.PrintfOk("nothing to install, bundle %s has no requirements", b.Repository())
+		return nil
+	}
+
+	bundles := make([]*bundle.Bundle, 0, len(b.LockFile.Require.List))
+	for _, r := range b.LockFile.Require.List {
+		v, err := bundle.ParseVersionExpr(r.Version)
+		if err != nil {
+			return err
+		}
+
+		formatted := bundleutil.FormatSourceWithVersion(r.Source, r.Version)
+		if v != nil && opts.storage.Some(r.Source, v.String()) {
+			opts.io.PrintfOk("bundle %s is already installed", formatted)
+			continue
+		}
+
+		// the lock file already contains the whole list of
+		// transitive requirements, so there is no need to
+		// go deeper into each of them
+		required, err := opts.fetcher.PlainFetch(ctx, r.Source, v)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %v", formatted, err)
+		}
+
+		if !opts.inspector.Report(formatted, opts.inspector.CheckRequirement(required, r)) {
+			return fmt.Errorf("%s: downloaded bundle does not match %s", formatted, constant.LockFileName)
+		}
+
+		bundles = append(bundles, required)
+	}
+
+	return storeAll(opts, bundles)
+}
+
+func storeAll(opts *installOptions, bundles []*bundle.Bundle) error {
+	for _, b := range bundles {
+		// cannot save a bundle without a version, since
+		// it is impossible to obtain it correctly later
+		if b.Version == nil {
+			continue
+		}
+
+		formatted := bundleutil.FormatSourceWithVersion(b.Repository(), b.Version.String())
+		if opts.storage.Some(b.Repository(), b.Version.String()) {
+			opts.io.PrintfOk("bundle %s is already installed", formatted)
+			continue
+		}
+
+		if err := opts.storage.StoreSome(b); err != nil {
+			return err
+		}
+
+		opts.io.PrintfOk("bundle %s has been added to %s", formatted, opts.storage.Dir)
+	}
 
 	return nil
 }
+
+// splitSourceVersion separates the version from the repository if it
+// was specified in the form of 'REPOSITORY@version'
+func splitSourceVersion(arg string, flagVersion string) (source string, version string, err error) {
+	idx := strings.LastIndex(arg, "@")
+	if idx == -1 || strings.ContainsAny(arg[idx+1:], "/:") {
+		// symbol '@' may belong to the repository itself, e.g. 'git@host:repo'
+		return arg, flagVersion, nil
+	}
+
+	source, version = arg[:idx], arg[idx+1:]
+	if flagVersion != "" && flagVersion != version {
+		return "", "", fmt.Errorf("conflicting versions '%s' and '%s' specified for %s", version, flagVersion, source)
+	}
+
+	return source, version, nil
+}
@@ -0,0 +1,179 @@
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/cli/cmdutil/table"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/spf13/cobra"
+)
+
+const cmdListDesc = `
+The 'bpm list' command prints the requirements recorded in the lock file
+of the bundle located at PATH (the current directory by default): their
+source, name, version, direction, checksums, and whether the bundle is
+present in the local storage ($BPM_PATH).
+
+Use '--all' to also list the modules of the bundle itself, together with
+their visibility, and '--json' to print the result as a JSON document.
+`
+
+func NewCmdList(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "List the requirements of a bundle",
+		Long:  cmdListDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonOutput, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+
+			all, err := cmd.Flags().GetBool("all")
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return listRun(&listOptions{
+				io:      f.IOStream,
+				dir:     dir,
+				json:    jsonOutput,
+				all:     all,
+				storage: f.Storage,
+			})
+		},
+	}
+
+	cmd.Flags().Bool("json", false, "Print the result as JSON")
+	cmd.Flags().Bool("all", false, "Also list the modules of the bundle")
+	return cmd
+}
+
+type listOptions struct {
+	io      core.IO
+	dir     string // bundle directory
+	json    bool   // print the result as JSON
+	all     bool   // include the bundle modules
+	storage *storage.Storage
+}
+
+type requirementEntry struct {
+	Source    string `json:"source"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Direction string `json:"direction"`
+	H1        string `json:"h1"`
+	H2        string `json:"h2"`
+	Cached    bool   `json:"cached"` // bundle is present in the local storage
+}
+
+type moduleEntry struct {
+	Package    string `json:"package"`
+	Visibility string `json:"visibility"`
+	Source     string `json:"source"`
+}
+
+type listOutput struct {
+	Requirements []*requirementEntry `json:"requirements"`
+	Modules      []*moduleEntry      `json:"modules,omitempty"`
+}
+
+func listRun(opts *listOptions) error {
+	// only the manifests are read, since nothing
+	// else is needed and the bundle can be out of sync
+	_, lockFile, err := opts.storage.LoadManifests(opts.dir)
+	if err != nil {
+		return err
+	}
+
+	output := &listOutput{
+		Requirements: make([]*requirementEntry, 0, len(lockFile.Require.List)),
+	}
+
+	for _, r := range lockFile.Require.List {
+		output.Requirements = append(output.Requirements, &requirementEntry{
+			Source:    r.Source,
+			Name:      r.Name,
+			Version:   r.Version,
+			Direction: r.Direction,
+			H1:        r.H1,
+			H2:        r.H2,
+			Cached:    opts.storage.Some(r.Source, r.Version),
+		})
+	}
+
+	if opts.all {
+		output.Modules = make([]*moduleEntry, 0, len(lockFile.Consist.List))
+		for _, m := range lockFile.Consist.Sort().List {
+			output.Modules = append(output.Modules, &moduleEntry{
+				Package:    m.Package,
+				Visibility: m.Visibility,
+				Source:     m.Source,
+			})
+		}
+	}
+
+	if opts.json {
+		encoder := json.NewEncoder(opts.io.GetStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	}
+
+	return printOutput(opts.io.GetStdout(), output)
+}
+
+func printOutput(w io.Writer, output *listOutput) error {
+	requirements := table.New("SOURCE", "NAME", "VERSION", "DIRECTION", "H1", "H2", "CACHED")
+	for _, r := range output.Requirements {
+		requirements.Append(r.Source, r.Name, r.Version, r.Direction, shortSum(r.H1), shortSum(r.H2), yesNo(r.Cached))
+	}
+
+	if requirements.Len() == 0 {
+		fmt.Fprintln(w, "no requirements")
+	} else if err := requirements.Print(w); err != nil {
+		return err
+	}
+
+	if output.Modules == nil {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+
+	modules := table.New("PACKAGE", "VISIBILITY", "SOURCE")
+	for _, m := range output.Modules {
+		modules.Append(m.Package, m.Visibility, m.Source)
+	}
+
+	return modules.Print(w)
+}
+
+// shortSum shortens the checksum the same way commit hashes
+// are shortened in versions, the full value is printed with '--json'
+func shortSum(sum string) string {
+	if len(sum) <= bundle.VersionShortHashLen {
+		return sum
+	}
+
+	return sum[:bundle.VersionShortHashLen]
+}
+
+func yesNo(ok bool) string {
+	if ok {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -0,0 +1,202 @@
+package outdated
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/cli/cmdutil/table"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/hashicorp/go-version"
+	"github.com/spf13/cobra"
+)
+
+const cmdOutdatedDesc = `
+The 'bpm outdated' command lists the requirements of the bundle located at
+PATH (the current directory by default) that have newer tagged versions than
+the versions recorded in the lock file. Only the tags of the requirement
+repositories are listed, nothing is downloaded.
+
+For every outdated requirement the current version, the newest compatible
+version and the newest version are printed. A version is compatible if it
+satisfies the declared constraint of a direct requirement, or otherwise if
+it has the same major version as the current one.
+
+Use '--fail' to exit with an error if any requirement is outdated, e.g. in CI.
+`
+
+func NewCmdOutdated(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outdated [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "List requirements that have newer versions",
+		Long:  cmdOutdatedDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonOutput, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+
+			fail, err := cmd.Flags().GetBool("fail")
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return outdatedRun(cmd.Context(), &outdatedOptions{
+				io:      f.IOStream,
+				dir:     dir,
+				json:    jsonOutput,
+				fail:    fail,
+				storage: f.Storage,
+				fetcher: f.Fetcher,
+			})
+		},
+	}
+
+	cmd.Flags().Bool("json", false, "Print the result as JSON")
+	cmd.Flags().Bool("fail", false, "Exit with an error if any requirement is outdated")
+	return cmd
+}
+
+type outdatedOptions struct {
+	io      core.IO
+	dir     string // bundle directory
+	json    bool   // print the result as JSON
+	fail    bool   // fail if any requirement is outdated
+	storage *storage.Storage
+	fetcher *fetch.Fetcher
+}
+
+type outdatedEntry struct {
+	Source     string `json:"source"`
+	Name       string `json:"name"`
+	Direction  string `json:"direction"`
+	Current    string `json:"current"`
+	Compatible string `json:"compatible"` // newest version compatible with the requirement
+	Latest     string `json:"latest"`
+}
+
+func outdatedRun(ctx context.Context, opts *outdatedOptions) error {
+	bundleFile, lockFile, err := opts.storage.LoadManifests(opts.dir)
+	if err != nil {
+		return err
+	}
+
+	result := make([]*outdatedEntry, 0)
+	for _, r := range lockFile.Require.List {
+		current, err := bundle.ParseVersionExpr(r.Version)
+		if err != nil || current == nil || current.IsConstraint() || current.IsPseudo() {
+			continue // versions of untagged commits cannot be compared with tags
+		}
+
+		source, declared := declaredRequirement(bundleFile, r)
+
+		versions, err := opts.fetcher.ListVersions(ctx, source)
+		if err != nil {
+			return err
+		}
+
+		compatible, latest := newestVersions(versions, current.SemTag, declared)
+		if !latest.GreaterThan(current.SemTag) {
+			continue
+		}
+
+		result = append(result, &outdatedEntry{
+			Source:     r.Source,
+			Name:       r.Name,
+			Direction:  r.Direction,
+			Current:    r.Version,
+			Compatible: compatible.Original(),
+			Latest:     latest.Original(),
+		})
+	}
+
+	if opts.json {
+		encoder := json.NewEncoder(opts.io.GetStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else if len(result) > 0 {
+		t := table.New("SOURCE", "NAME", "DIRECTION", "CURRENT", "COMPATIBLE", "LATEST")
+		for _, e := range result {
+			t.Append(e.Source, e.Name, e.Direction, e.Current, e.Compatible, e.Latest)
+		}
+
+		if err := t.Print(opts.io.GetStdout()); err != nil {
+			return err
+		}
+	} else {
+		opts.io.PrintfOk("all requirements are up to date")
+	}
+
+	if opts.fail && len(result) > 0 {
+		return fmt.Errorf("%d of %d requirements are outdated", len(result), len(lockFile.Require.List))
+	}
+
+	return nil
+}
+
+// declaredRequirement returns the source that should be used to list the
+// versions and the declared constraint, if the requirement is a direct one
+func declaredRequirement(bundleFile *bundlefile.Schema, r *lockfile.RequirementDecl) (string, *bundle.VersionConstraint) {
+	if r.Direction != lockfile.Direct.String() {
+		return r.Source, nil
+	}
+
+	for _, decl := range bundleFile.Require.List {
+		if remote.Redact(decl.Source) != r.Source {
+			continue
+		}
+
+		// the lock file source never contains credentials,
+		// so the declared one is used to list the versions
+		v, err := bundle.ParseVersionExpr(decl.Version)
+		if err != nil || !v.IsConstraint() {
+			return decl.Source, nil
+		}
+
+		return decl.Source, v.Constraint
+	}
+
+	return r.Source, nil
+}
+
+// newestVersions returns the newest version that is compatible with the
+// current one and the newest version overall, pre-releases are skipped
+func newestVersions(versions []*version.Version, current *version.Version, declared *bundle.VersionConstraint) (compatible, latest *version.Version) {
+	compatible, latest = current, current
+	for _, v := range versions {
+		if v.Prerelease() != "" {
+			continue
+		}
+
+		if v.GreaterThan(latest) {
+			latest = v
+		}
+
+		isCompatible := v.Segments()[0] == current.Segments()[0]
+		if declared != nil {
+			isCompatible = declared.Check(v)
+		}
+
+		if isCompatible && v.GreaterThan(compatible) {
+			compatible = v
+		}
+	}
+
+	return compatible, latest
+}
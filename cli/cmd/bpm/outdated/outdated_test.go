@@ -0,0 +1,43 @@
+package outdated
+
+import (
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewestVersions(t *testing.T) {
+	parse := func(tags ...string) []*version.Version {
+		result := make([]*version.Version, 0, len(tags))
+		for _, tag := range tags {
+			result = append(result, version.Must(version.NewVersion(tag)))
+		}
+
+		return result
+	}
+
+	current := version.Must(version.NewVersion("v1.0.0"))
+
+	t.Run("Pre-release should be skipped", func(t *testing.T) {
+		compatible, latest := newestVersions(parse("v1.0.0", "v1.9.0", "v1.10.0", "v2.0.0-rc.1"), current, nil)
+		require.Equal(t, "v1.10.0", compatible.Original())
+		require.Equal(t, "v1.10.0", latest.Original())
+	})
+
+	t.Run("Newer major version should only be the latest one", func(t *testing.T) {
+		compatible, latest := newestVersions(parse("v1.0.0", "v1.9.0", "v2.0.0"), current, nil)
+		require.Equal(t, "v1.9.0", compatible.Original())
+		require.Equal(t, "v2.0.0", latest.Original())
+	})
+
+	t.Run("Declared constraint should limit the compatible version", func(t *testing.T) {
+		declared, err := bundle.ParseVersionConstraint("< 1.5")
+		require.NoError(t, err)
+
+		compatible, latest := newestVersions(parse("v1.0.0", "v1.4.0", "v1.9.0"), current, declared)
+		require.Equal(t, "v1.4.0", compatible.Original())
+		require.Equal(t, "v1.9.0", latest.Original())
+	})
+}
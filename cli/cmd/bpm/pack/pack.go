@@ -0,0 +1,157 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/bundleutil/inspect"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
+	"github.com/spf13/cobra"
+)
+
+const cmdPackDesc = `
+The 'bpm pack' command packs the bundle located at PATH (the current
+directory by default) into a '.tar.gz' archive, that can be distributed
+or uploaded to a bundle registry (see 'bpm serve').
+
+The archive contains 'bundle.hcl', 'lockfile.hcl', '.bpmignore', rego
+files and all other files of the bundle, except the ignored ones. It is
+reproducible: entries are sorted, and modification times, owners and
+permissions are fixed, so packing the same bundle always produces the
+same archive.
+
+The bundle must be tidy, i.e. its contents must match the checksum
+recorded in the 'lockfile.hcl'.
+`
+
+func NewCmdPack(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "Pack a bundle into a reproducible archive",
+		Long:  cmdPackDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+
+			dir, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return packRun(&packOptions{
+				io:        f.IOStream,
+				dir:       dir,
+				output:    output,
+				osWrap:    f.OS,
+				storage:   f.Storage,
+				inspector: f.Inspector,
+				encoder:   f.Encoder,
+			})
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Archive file path (default '<name>.tar.gz')")
+	return cmd
+}
+
+type packOptions struct {
+	io        core.IO
+	dir       string // bundle directory
+	output    string // archive file path
+	osWrap    osiface.OSWrapper
+	storage   *storage.Storage
+	inspector *inspect.Inspector
+	encoder   *encode.Encoder
+}
+
+func packRun(opts *packOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	output := opts.output
+	if output == "" {
+		output = b.Name() + ".tar.gz"
+	}
+
+	// an archive produced earlier into the bundle directory
+	// must not end up inside of the new one
+	if rel, err := relativePath(opts.dir, output); err == nil {
+		delete(b.OtherFiles, rel)
+	}
+
+	if err := opts.inspector.Inspect(b); err != nil {
+		return fmt.Errorf("%v\n\t> run 'bpm tidy' to update %s", err, b.LockFile.Filename())
+	}
+
+	var buf bytes.Buffer
+	if err := archive.WriteTarGz(&buf, archive.BundleFiles(b, opts.encoder)); err != nil {
+		return fmt.Errorf("failed to pack %s: %v", b.Repository(), err)
+	}
+
+	// making sure that the bundle unpacked from the
+	// archive is exactly the same as the original one
+	files, err := archive.ReadTarGz(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	unpacked, err := archive.DecodeBundle(files, opts.encoder)
+	if err != nil {
+		return err
+	}
+
+	if unpacked.Sum() != b.Sum() {
+		return fmt.Errorf("checksum of the packed bundle does not match\n\t> expected: %s,\n\t> actual: %s", b.Sum(), unpacked.Sum())
+	}
+
+	if err := opts.osWrap.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(buf.Bytes())
+	opts.io.PrintfOk("bundle %s has been packed to %s", b.Repository(), filepath.Clean(output))
+	opts.io.Printf("h2: %s\nsha256: %s\n", b.Sum(), hex.EncodeToString(hash[:]))
+
+	return nil
+}
+
+// relativePath returns the path of the file relative to the directory,
+// or an error if the file is located outside of the directory
+func relativePath(dir, file string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(absDir, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file %s is outside of %s", file, dir)
+	}
+
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,87 @@
+package remove
+
+import (
+	"context"
+	"os"
+
+	"github.com/4rchr4y/bpm/bundleutil/manifest"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/spf13/cobra"
+)
+
+const cmdRemoveDesc = `
+The 'bpm remove' command removes the requirement of the bundle SOURCE
+from the bundle in the current directory and synchronizes the lock file,
+dropping the indirect requirements that are no longer used.
+
+The command refuses to remove a bundle that is still imported by any of
+the rego files, and lists such imports. Use '--force' to remove it anyway.
+`
+
+func NewCmdRemove(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove SOURCE",
+		Short: "Remove a dependency",
+		Long:  cmdRemoveDesc,
+		Args:  require.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				return err
+			}
+
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			return removeRun(cmd.Context(), &removeOptions{
+				io:         f.IOStream,
+				workDir:    wd,
+				source:     args[0],
+				force:      force,
+				storage:    f.Storage,
+				manifester: f.Manifester,
+			})
+		},
+	}
+
+	cmd.Flags().Bool("force", false, "Remove the bundle even if it is still imported")
+	return cmd
+}
+
+type removeOptions struct {
+	io         core.IO
+	workDir    string // bundle working directory
+	source     string // bundle repository that needs to be removed
+	force      bool
+	storage    *storage.Storage
+	manifester *manifest.Manifester // bundle manifest file control operator
+}
+
+func removeRun(ctx context.Context, opts *removeOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.workDir, nil)
+	if err != nil {
+		return err
+	}
+
+	input := &manifest.RemoveRequirementInput{
+		Parent: b,
+		Source: opts.source,
+		Force:  opts.force,
+	}
+
+	if err := opts.manifester.RemoveRequirement(ctx, input); err != nil {
+		return err
+	}
+
+	if err := opts.manifester.Upgrade(opts.workDir, b); err != nil {
+		return err
+	}
+
+	opts.io.PrintfOk("bundle %s has been removed", opts.source)
+	return nil
+}
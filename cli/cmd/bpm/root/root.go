@@ -5,11 +5,25 @@ import (
 	"github.com/4rchr4y/bpm/core"
 	"github.com/spf13/cobra"
 
+	cmdBuild "github.com/4rchr4y/bpm/cli/cmd/bpm/build"
+	cmdDownload "github.com/4rchr4y/bpm/cli/cmd/bpm/download"
+	cmdEval "github.com/4rchr4y/bpm/cli/cmd/bpm/eval"
+	cmdFmt "github.com/4rchr4y/bpm/cli/cmd/bpm/fmt"
 	cmdGet "github.com/4rchr4y/bpm/cli/cmd/bpm/get"
+	cmdGraph "github.com/4rchr4y/bpm/cli/cmd/bpm/graph"
 	cmdInit "github.com/4rchr4y/bpm/cli/cmd/bpm/init"
 	cmdInstall "github.com/4rchr4y/bpm/cli/cmd/bpm/install"
+	cmdList "github.com/4rchr4y/bpm/cli/cmd/bpm/list"
+	cmdOutdated "github.com/4rchr4y/bpm/cli/cmd/bpm/outdated"
+	cmdPack "github.com/4rchr4y/bpm/cli/cmd/bpm/pack"
+	cmdRemove "github.com/4rchr4y/bpm/cli/cmd/bpm/remove"
+	cmdServe "github.com/4rchr4y/bpm/cli/cmd/bpm/serve"
+	cmdTest "github.com/4rchr4y/bpm/cli/cmd/bpm/test"
 	cmdTidy "github.com/4rchr4y/bpm/cli/cmd/bpm/tidy"
+	cmdUpdate "github.com/4rchr4y/bpm/cli/cmd/bpm/update"
+	cmdVerify "github.com/4rchr4y/bpm/cli/cmd/bpm/verify"
 	cmdVersion "github.com/4rchr4y/bpm/cli/cmd/bpm/version"
+	cmdWhy "github.com/4rchr4y/bpm/cli/cmd/bpm/why"
 )
 
 func NewCmdRoot(f *factory.Factory, version string) (*cobra.Command, error) {
@@ -45,6 +59,20 @@ func NewCmdRoot(f *factory.Factory, version string) (*cobra.Command, error) {
 	cmd.AddCommand(cmdInstall.NewCmdInstall(f))
 	cmd.AddCommand(cmdTidy.NewCmdTidy(f))
 	cmd.AddCommand(cmdGet.NewCmdGet(f))
+	cmd.AddCommand(cmdRemove.NewCmdRemove(f))
+	cmd.AddCommand(cmdUpdate.NewCmdUpdate(f))
+	cmd.AddCommand(cmdOutdated.NewCmdOutdated(f))
+	cmd.AddCommand(cmdVerify.NewCmdVerify(f))
+	cmd.AddCommand(cmdList.NewCmdList(f))
+	cmd.AddCommand(cmdGraph.NewCmdGraph(f))
+	cmd.AddCommand(cmdWhy.NewCmdWhy(f))
+	cmd.AddCommand(cmdDownload.NewCmdDownload(f))
+	cmd.AddCommand(cmdServe.NewCmdServe(f))
+	cmd.AddCommand(cmdPack.NewCmdPack(f))
+	cmd.AddCommand(cmdBuild.NewCmdBuild(f))
+	cmd.AddCommand(cmdEval.NewCmdEval(f))
+	cmd.AddCommand(cmdTest.NewCmdTest(f))
+	cmd.AddCommand(cmdFmt.NewCmdFmt(f))
 
 	return cmd, nil
 }
@@ -0,0 +1,117 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/pkg/proxy"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/spf13/cobra"
+)
+
+const cmdServeDesc = `
+The 'bpm serve' command starts a bundle registry that serves the bundles
+of the local storage ($BPM_PATH by default) over HTTP, using the same
+protocol as bundle proxies:
+
+	GET /{source}/@v/list
+	GET /{source}/@v/{version}.info
+	GET /{source}/@v/{version}.zip
+
+The registry can be used by setting 'BPM_PROXY', e.g.
+'BPM_PROXY=http://localhost:8080,direct'.
+
+If 'BPM_SERVE_TOKEN' is set, packed bundles can also be uploaded with
+'PUT /{source}/@v/{version}.zip' and 'Authorization: Bearer <token>'
+header. Uploaded bundles are inspected before they are accepted, and
+existing versions can never be replaced.
+`
+
+func NewCmdServe(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Args:  require.NoArgs,
+		Short: "Serve bundles of the local storage over HTTP",
+		Long:  cmdServeDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString("addr")
+			if err != nil {
+				return err
+			}
+
+			dir, err := cmd.Flags().GetString("dir")
+			if err != nil {
+				return err
+			}
+
+			s := f.Storage
+			if dir != "" {
+				s = &storage.Storage{
+					Dir:     dir,
+					IO:      f.Storage.IO,
+					OSWrap:  f.Storage.OSWrap,
+					IOWrap:  f.Storage.IOWrap,
+					Encoder: f.Storage.Encoder,
+				}
+			}
+
+			return serveRun(cmd.Context(), &serveOptions{
+				io:   f.IOStream,
+				addr: addr,
+				dir:  s.Dir,
+				server: &proxy.Server{
+					IO:        f.IOStream,
+					Storage:   s,
+					Inspector: f.Inspector,
+					Encoder:   f.Encoder,
+					Token:     os.Getenv("BPM_SERVE_TOKEN"),
+				},
+			})
+		},
+	}
+
+	cmd.Flags().String("addr", ":8080", "Address to listen on")
+	cmd.Flags().String("dir", "", "Storage directory to serve bundles from (default $BPM_PATH)")
+	return cmd
+}
+
+type serveOptions struct {
+	io     core.IO
+	addr   string // address to listen on
+	dir    string // storage directory
+	server *proxy.Server
+}
+
+func serveRun(ctx context.Context, opts *serveOptions) error {
+	srv := &http.Server{
+		Addr:              opts.addr,
+		Handler:           opts.server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if opts.server.Token == "" {
+		opts.io.PrintfWarn("uploads are disabled, set BPM_SERVE_TOKEN to enable them")
+	}
+
+	opts.io.PrintfInfo("serving bundles from %s on %s", opts.dir, opts.addr)
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/pkg/linker"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
+	opastorage "github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/tester"
+	"github.com/spf13/cobra"
+)
+
+const cmdTestDesc = `
+The 'bpm test' command runs the rego tests of the bundle located at PATH
+(the current directory by default). Tests are the rules with the 'test_'
+prefix, they are evaluated together with all modules and data of the
+bundle requirements linked the same way as 'bpm build' does it, so the
+tests can import requirements by their bundle names. Tests of the
+requirements themselves are not run.
+
+The '--run' flag limits the tests to those whose full path, for example
+'data.mybundle.rules.test_allow', matches the regular expression.
+
+With '--coverage' the coverage report of the bundle modules is printed
+as JSON instead of the test results, and '--threshold' sets the minimal
+coverage percentage that is required for the command to succeed.
+
+The command exits with a non-zero code if any test fails or cannot be
+evaluated, or if the coverage is below the threshold.
+`
+
+func NewCmdTest(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "Run the rego tests of a bundle",
+		Long:  cmdTestDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run, err := cmd.Flags().GetString("run")
+			if err != nil {
+				return err
+			}
+
+			verbose, err := cmd.Flags().GetBool("verbose")
+			if err != nil {
+				return err
+			}
+
+			coverage, err := cmd.Flags().GetBool("coverage")
+			if err != nil {
+				return err
+			}
+
+			threshold, err := cmd.Flags().GetFloat64("threshold")
+			if err != nil {
+				return err
+			}
+
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return testRun(cmd.Context(), &testOptions{
+				io:        f.IOStream,
+				dir:       dir,
+				run:       run,
+				verbose:   verbose,
+				coverage:  coverage,
+				threshold: threshold,
+				storage:   f.Storage,
+				linker:    f.Linker,
+			})
+		},
+	}
+
+	cmd.Flags().String("run", "", "Run only the tests matching the regular expression")
+	cmd.Flags().BoolP("verbose", "v", false, "Print the result of every test and the traces of failures")
+	cmd.Flags().Bool("coverage", false, "Print the coverage report of the bundle modules")
+	cmd.Flags().Float64("threshold", 0, "Minimal coverage percentage required with '--coverage'")
+	return cmd
+}
+
+type testOptions struct {
+	io        core.IO
+	dir       string // bundle directory
+	run       string // test name filter
+	verbose   bool
+	coverage  bool
+	threshold float64 // minimal coverage percentage
+	storage   *storage.Storage
+	linker    *linker.Linker
+}
+
+func testRun(ctx context.Context, opts *testOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	linked, err := opts.linker.LinkBundles(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	// only the tests of the head bundle are run
+	for _, lb := range linked[1:] {
+		for filePath, m := range lb.Modules {
+			lb.Modules[filePath] = withoutTests(m)
+		}
+	}
+
+	result, err := linker.Build(linked)
+	if err != nil {
+		return err
+	}
+
+	modules := make(map[string]*ast.Module, len(result.Modules))
+	for _, mf := range result.Modules {
+		modules[mf.Path] = mf.Parsed
+	}
+
+	store := inmem.NewFromObject(result.Data)
+	txn, err := store.NewTransaction(ctx, opastorage.TransactionParams{})
+	if err != nil {
+		return err
+	}
+	defer store.Abort(ctx, txn)
+
+	runner := tester.NewRunner().
+		SetCompiler(ast.NewCompiler().WithEnablePrintStatements(true)).
+		SetStore(store).
+		SetModules(modules).
+		Filter(opts.run).
+		EnableTracing(opts.verbose).
+		CapturePrintOutput(true)
+
+	cov := cover.New()
+	if opts.coverage {
+		runner.SetCoverageQueryTracer(cov)
+	}
+
+	ch, err := runner.RunTests(ctx, txn)
+	if err != nil {
+		return fmt.Errorf("failed to compile %s: %v", b.Repository(), err)
+	}
+
+	results := make([]*tester.Result, 0)
+	for r := range ch {
+		results = append(results, r)
+	}
+
+	if len(results) == 0 {
+		opts.io.PrintfWarn("no tests found in %s", b.Repository())
+		return nil
+	}
+
+	var reporter tester.Reporter = tester.PrettyReporter{
+		Output:      opts.io.GetStdout(),
+		Verbose:     opts.verbose,
+		FailureLine: true,
+	}
+
+	if opts.coverage {
+		reporter = tester.JSONCoverageReporter{
+			Cover:     cov,
+			Modules:   linked[0].Modules,
+			Output:    opts.io.GetStdout(),
+			Threshold: opts.threshold,
+			Verbose:   opts.verbose,
+		}
+	}
+
+	if err := reporter.Report(replay(results)); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, r := range results {
+		if !r.Pass() && !r.Skip {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed in %s", failed, len(results), b.Repository())
+	}
+
+	return nil
+}
+
+// withoutTests returns a copy of the module without test rules
+func withoutTests(m *ast.Module) *ast.Module {
+	result := m.Copy()
+	rules := result.Rules
+	result.Rules = make([]*ast.Rule, 0, len(rules))
+
+	for _, rule := range rules {
+		name := ruleName(rule.Head)
+		if strings.HasPrefix(name, tester.TestPrefix) || strings.HasPrefix(name, tester.SkipTestPrefix) {
+			continue
+		}
+
+		result.Rules = append(result.Rules, rule)
+	}
+
+	return result
+}
+
+// ruleName returns the last segment of the rule reference
+// that the runner checks for the test prefixes
+func ruleName(h *ast.Head) string {
+	ref := h.Ref()
+	switch last := ref[len(ref)-1].Value.(type) {
+	case ast.Var:
+		return string(last)
+	case ast.String:
+		return string(last)
+	default:
+		return ""
+	}
+}
+
+// replay sends collected results into a closed channel for a reporter
+func replay(results []*tester.Result) chan *tester.Result {
+	ch := make(chan *tester.Result, len(results))
+	for _, r := range results {
+		ch <- r
+	}
+	close(ch)
+
+	return ch
+}
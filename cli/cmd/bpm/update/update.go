@@ -0,0 +1,139 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/4rchr4y/bpm/bundleutil/manifest"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/table"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/spf13/cobra"
+)
+
+const cmdUpdateDesc = `
+The 'bpm update' command updates the direct requirements of the bundle in
+the current directory to their newest tagged versions. Only the requirements
+with the given SOURCEs are updated, or all of them if no SOURCE is given.
+
+By default any newer version is allowed. Use '--minor' to keep the major
+version, or '--patch' to keep both the major and the minor version.
+Requirements declared as version constraints are only updated within
+the constraint.
+
+The updated versions are printed as a table. With '--dry-run' nothing
+is written, otherwise both the bundle file and the lock file are rewritten.
+`
+
+func NewCmdUpdate(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update [SOURCE...]",
+		Short: "Update dependencies to newer versions",
+		Long:  cmdUpdateDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			patch, err := cmd.Flags().GetBool("patch")
+			if err != nil {
+				return err
+			}
+
+			minor, err := cmd.Flags().GetBool("minor")
+			if err != nil {
+				return err
+			}
+
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				return err
+			}
+
+			if patch && minor {
+				return errors.New("flags '--patch' and '--minor' cannot be used together")
+			}
+
+			limit := manifest.UpdateLatest
+			switch {
+			case patch:
+				limit = manifest.UpdatePatch
+			case minor:
+				limit = manifest.UpdateMinor
+			}
+
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			return updateRun(cmd.Context(), &updateOptions{
+				io:         f.IOStream,
+				workDir:    wd,
+				sources:    args,
+				limit:      limit,
+				dryRun:     dryRun,
+				storage:    f.Storage,
+				manifester: f.Manifester,
+			})
+		},
+	}
+
+	cmd.Flags().Bool("patch", false, "Only update to newer patch versions")
+	cmd.Flags().Bool("minor", false, "Only update to newer minor and patch versions")
+	cmd.Flags().Bool("dry-run", false, "Print available updates without applying them")
+	return cmd
+}
+
+type updateOptions struct {
+	io         core.IO
+	workDir    string   // bundle working directory
+	sources    []string // requirements that need to be updated, all if empty
+	limit      manifest.UpdateLimit
+	dryRun     bool
+	storage    *storage.Storage
+	manifester *manifest.Manifester // bundle manifest file control operator
+}
+
+func updateRun(ctx context.Context, opts *updateOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.workDir, nil)
+	if err != nil {
+		return err
+	}
+
+	updates, err := opts.manifester.FindUpdates(ctx, &manifest.FindUpdatesInput{
+		Parent:  b,
+		Sources: opts.sources,
+		Limit:   opts.limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(updates) == 0 {
+		opts.io.PrintfOk("all requirements are up to date")
+		return nil
+	}
+
+	t := table.New("SOURCE", "NAME", "OLD", "NEW")
+	for _, u := range updates {
+		t.Append(u.Source, u.Name, u.Old.String(), u.New.String())
+	}
+
+	if err := t.Print(opts.io.GetStdout()); err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		return nil
+	}
+
+	if err := opts.manifester.ApplyUpdates(ctx, b, updates); err != nil {
+		return err
+	}
+
+	if err := opts.manifester.Upgrade(opts.workDir, b); err != nil {
+		return err
+	}
+
+	opts.io.PrintfOk("%d requirements have been updated", len(updates))
+	return nil
+}
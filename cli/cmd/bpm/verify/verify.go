@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil"
+	"github.com/4rchr4y/bpm/bundleutil/inspect"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/spf13/cobra"
+)
+
+const cmdVerifyDesc = `
+The 'bpm verify' command checks the integrity of the bundle located at PATH
+(the current directory by default) and of its requirements stored in the
+local storage ($BPM_PATH).
+
+For every requirement recorded in the lock file, the checksum of its bundle
+file (h1) and the checksum of the whole bundle (h2) are recomputed from the
+stored files and compared with the recorded ones. The bundle itself is
+compared with the checksums of its own lock file. Every mismatching file is
+reported, so that corrupted or modified bundles are detected before the
+policies are deployed.
+`
+
+func NewCmdVerify(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [PATH]",
+		Args:  require.MaximumNArgs(1),
+		Short: "Verify the checksums of a bundle and its requirements",
+		Long:  cmdVerifyDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			return verifyRun(&verifyOptions{
+				io:        f.IOStream,
+				dir:       dir,
+				storage:   f.Storage,
+				inspector: f.Inspector,
+			})
+		},
+	}
+
+	return cmd
+}
+
+type verifyOptions struct {
+	io        core.IO
+	dir       string // bundle directory
+	storage   *storage.Storage
+	inspector *inspect.Inspector
+}
+
+func verifyRun(opts *verifyOptions) error {
+	b, err := opts.storage.LoadFromAbs(opts.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	if !opts.inspector.Report(b.Repository(), opts.inspector.CheckBundle(b)) {
+		opts.io.PrintfWarn("%s is out of date, run 'bpm tidy' if the changes are expected", constant.LockFileName)
+		failed++
+	}
+
+	for _, r := range b.LockFile.Require.List {
+		name := bundleutil.FormatSourceWithVersion(r.Source, r.Version)
+		if err := verifyRequirement(opts, r); err != nil {
+			opts.io.PrintfErr("%s: %v", name, err)
+			failed++
+		}
+	}
+
+	total := len(b.LockFile.Require.List) + 1
+	if failed > 0 {
+		return fmt.Errorf("%d of %d bundles failed verification", failed, total)
+	}
+
+	opts.io.PrintfOk("%d bundles verified", total)
+	return nil
+}
+
+func verifyRequirement(opts *verifyOptions, r *lockfile.RequirementDecl) error {
+	v, err := bundle.ParseVersionExpr(r.Version)
+	if err != nil {
+		return err
+	}
+
+	if !opts.storage.Some(r.Source, v.String()) {
+		return fmt.Errorf("bundle is not installed\n\t> run 'bpm install' to install it")
+	}
+
+	dep, err := opts.storage.Load(r.Source, v)
+	if err != nil {
+		return err
+	}
+
+	if !opts.inspector.Report(bundleutil.FormatSourceWithVersion(r.Source, r.Version), opts.inspector.CheckRequirement(dep, r)) {
+		return fmt.Errorf("stored bundle does not match %s", constant.LockFileName)
+	}
+
+	return nil
+}
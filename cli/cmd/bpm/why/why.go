@@ -0,0 +1,118 @@
+package why
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle/regofile"
+	"github.com/4rchr4y/bpm/cli/cmdutil/factory"
+	"github.com/4rchr4y/bpm/cli/cmdutil/require"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/pkg/graph"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/spf13/cobra"
+)
+
+const cmdWhyDesc = `
+The 'bpm why' command explains why the bundle SOURCE is required by the
+bundle in the current directory, by printing the shortest requirement
+chains that lead from the bundle to SOURCE. The lock files of the
+requirements are taken from the local storage.
+
+With '--module' the command lists every module of the bundle that imports
+the given module, along with the file and the line of the import, e.g.
+'bpm why --module data.policies.common'.
+`
+
+func NewCmdWhy(f *factory.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "why [SOURCE]",
+		Short: "Explain why a bundle or a module is required",
+		Long:  cmdWhyDesc,
+		Args:  require.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			module, err := cmd.Flags().GetString("module")
+			if err != nil {
+				return err
+			}
+
+			if (len(args) == 0) == (module == "") {
+				return errors.New("either SOURCE or '--module' must be specified")
+			}
+
+			opts := &whyOptions{
+				io:      f.IOStream,
+				dir:     ".",
+				module:  module,
+				storage: f.Storage,
+			}
+
+			if len(args) > 0 {
+				opts.source = args[0]
+			}
+
+			return whyRun(opts)
+		},
+	}
+
+	cmd.Flags().StringP("module", "m", "", "Module whose imports need to be explained")
+	return cmd
+}
+
+type whyOptions struct {
+	io      core.IO
+	dir     string // bundle directory
+	source  string // bundle source that needs to be explained
+	module  string // module that needs to be explained
+	storage *storage.Storage
+}
+
+func whyRun(opts *whyOptions) error {
+	if opts.module != "" {
+		return whyModule(opts)
+	}
+
+	g, err := graph.BuildFromStorage(opts.storage, opts.dir)
+	if err != nil {
+		return err
+	}
+
+	chains, err := g.ShortestChains(opts.source)
+	if err != nil {
+		return err
+	}
+
+	for _, chain := range chains {
+		opts.io.Println(strings.Join(chain, " -> "))
+	}
+
+	return nil
+}
+
+func whyModule(opts *whyOptions) error {
+	bundleFile, lockFile, err := opts.storage.LoadManifests(opts.dir)
+	if err != nil {
+		return err
+	}
+
+	edges, err := graph.ImportsOf(lockFile, opts.module)
+	if err != nil {
+		return err
+	}
+
+	module := strings.TrimPrefix(opts.module, regofile.ImportPathPrefix)
+	if len(edges) == 0 && bundleFile.Package != nil {
+		return fmt.Errorf("module %s%s is not imported by %s", regofile.ImportPathPrefix, module, bundleFile.Package.Repository)
+	}
+
+	if len(edges) == 0 {
+		return fmt.Errorf("module %s%s is not imported", regofile.ImportPathPrefix, module)
+	}
+
+	for _, e := range edges {
+		opts.io.Printf("%s:%d: %s imports %s%s\n", e.File, e.Line, e.From, regofile.ImportPathPrefix, e.To)
+	}
+
+	return nil
+}
@@ -1,14 +1,24 @@
 package factory
 
 import (
+	"net/http"
+	"path/filepath"
+
 	"github.com/4rchr4y/bpm/bundleutil/encode"
 	"github.com/4rchr4y/bpm/bundleutil/inspect"
 	"github.com/4rchr4y/bpm/bundleutil/manifest"
+	"github.com/4rchr4y/bpm/constant"
 	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/fetch/auth"
+	"github.com/4rchr4y/bpm/fetch/remote"
 	"github.com/4rchr4y/bpm/internal/service/github"
 	"github.com/4rchr4y/bpm/iostream"
+	"github.com/4rchr4y/bpm/pkg/linker"
+	"github.com/4rchr4y/bpm/pkg/proxy"
+	"github.com/4rchr4y/bpm/pkg/resolver"
 	"github.com/4rchr4y/bpm/storage"
 	"github.com/4rchr4y/godevkit/v3/env"
+	"github.com/4rchr4y/godevkit/v3/must"
 	"github.com/4rchr4y/godevkit/v3/syswrap"
 )
 
@@ -36,15 +46,40 @@ func New() *Factory {
 		IO: io,
 	}
 
+	sources := &remote.Resolver{
+		Rules: must.Must(remote.ParseRules(env.GetStringWithDefault("BPM_GIT_HOSTS", ""))),
+	}
+
+	direct := &fetch.GithubFetcher{
+		IO: io,
+		Client: &github.GitClient{
+			CacheDir: filepath.Join(dir, ".cache", "git"),
+		},
+		Encoder: encoder,
+		Sources: sources,
+		Auth: &auth.Authenticator{
+			OSWrap:          osWrap,
+			Helper:          &github.GitCLI{},
+			CredentialsFile: env.GetStringWithDefault("BPM_CREDENTIALS_FILE", filepath.Join(dir, constant.CredentialsFileName)),
+		},
+	}
+
+	proxies := must.Must(proxy.ParseSetting(env.GetStringWithDefault("BPM_PROXY", proxy.Direct)))
+
 	fetcher := &fetch.Fetcher{
 		IO:        io,
 		Storage:   storage,
 		Inspector: inspector,
-		GitHub: &fetch.GithubFetcher{
-			IO:      io,
-			Client:  &github.GitClient{},
-			Encoder: encoder,
-		},
+		GitHub: proxy.NewDownloader(proxies, direct, func(url string) *proxy.Client {
+			return &proxy.Client{
+				IO:      io,
+				URL:     url,
+				HTTP:    http.DefaultClient,
+				Encoder: encoder,
+			}
+		}),
+		Sources: sources,
+		Workers: env.GetIntWithDefault("BPM_FETCH_WORKERS", fetch.DefaultWorkers),
 	}
 
 	manifester := &manifest.Manifester{
@@ -53,6 +88,16 @@ func New() *Factory {
 		Storage: storage,
 		Encoder: encoder,
 		Fetcher: fetcher,
+		Resolver: &resolver.Resolver{
+			IO:      io,
+			Fetcher: fetcher,
+		},
+	}
+
+	linker := &linker.Linker{
+		Fetcher:    fetcher,
+		Manifester: manifester,
+		Inspector:  inspector,
 	}
 
 	f := &Factory{
@@ -65,6 +110,7 @@ func New() *Factory {
 		Storage:    storage,
 		GitCLI:     &github.GitCLI{},
 		Manifester: manifester,
+		Linker:     linker,
 		IO:         ioWrap,
 		OS:         osWrap,
 	}
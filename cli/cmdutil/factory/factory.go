@@ -7,6 +7,7 @@ import (
 	"github.com/4rchr4y/bpm/core"
 	"github.com/4rchr4y/bpm/fetch"
 	"github.com/4rchr4y/bpm/internal/service/github"
+	"github.com/4rchr4y/bpm/pkg/linker"
 	"github.com/4rchr4y/bpm/storage"
 	"github.com/4rchr4y/godevkit/v3/syswrap/ioiface"
 	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
@@ -24,6 +25,7 @@ type Factory struct {
 	GitCLI     *github.GitCLI
 	Fetcher    *fetch.Fetcher
 	Manifester *manifest.Manifester // bundle manifest file control operator
+	Linker     *linker.Linker       // links bundles with their requirements
 	OS         osiface.OSWrapper    // set of functions for working with the OS
 	IO         ioiface.IOWrapper    // set of functions for working with input/output
 }
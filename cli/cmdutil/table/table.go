@@ -0,0 +1,40 @@
+package table
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Table collects rows and prints them aligned in columns
+type Table struct {
+	header []string
+	rows   [][]string
+}
+
+func New(header ...string) *Table {
+	return &Table{
+		header: header,
+		rows:   make([][]string, 0),
+	}
+}
+
+// Append adds a row, missing cells are left empty
+func (t *Table) Append(row ...string) { t.rows = append(t.rows, row) }
+
+func (t *Table) Len() int { return len(t.rows) }
+
+// Print writes the header followed by all rows
+func (t *Table) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		cells := make([]string, len(t.header))
+		copy(cells, row)
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -3,3 +3,4 @@ package constant
 const BundleFileName = "bundle.hcl"
 const LockFileName = "lockfile.hcl"
 const IgnoreFileName = ".bpmignore"
+const CredentialsFileName = "credentials.hcl"
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/4rchr4y/godevkit/v3/syswrap/osiface"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+const (
+	// TokenEnvPrefix is the prefix of env variables with access tokens for
+	// a particular host, e.g. 'BPM_GIT_TOKEN_GITHUB_COM' for 'github.com'
+	TokenEnvPrefix = "BPM_GIT_TOKEN_"
+
+	// UsernameEnvPrefix is the prefix of env variables with user
+	// names that are sent along with the token of the host
+	UsernameEnvPrefix = "BPM_GIT_USERNAME_"
+
+	// DefaultTokenUsername is sent as a user name if it is not specified,
+	// since most git hosts only check the token itself
+	DefaultTokenUsername = "bpm"
+
+	DefaultSSHUser = "git"
+)
+
+// defaultKeyFiles are looked up in '~/.ssh' if
+// neither ssh agent nor key file is specified
+var defaultKeyFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+type authHelper interface {
+	FillCredential(ctx context.Context, protocol, host, path string) (username, password string, err error)
+}
+
+// Authenticator provides credentials to access remote repositories.
+// For http(s) the credentials are looked up in the following order:
+//
+//   - user and password specified in the source URL;
+//   - 'BPM_GIT_TOKEN_<HOST>' and 'BPM_GIT_USERNAME_<HOST>' env variables;
+//   - credentials file entry of the host;
+//   - git credential helper, if configured.
+//
+// For ssh a key file from the credentials file or 'BPM_SSH_KEY' env
+// variable is used first, then ssh agent and default key files.
+type Authenticator struct {
+	OSWrap          osiface.OSWrapper
+	Helper          authHelper // optional git credential helper
+	CredentialsFile string     // path to the credentials file, optional
+
+	once  sync.Once
+	creds *CredentialsFile
+	err   error
+}
+
+func (a *Authenticator) Method(ctx context.Context, ep *remote.Endpoint) (transport.AuthMethod, error) {
+	switch ep.Transport {
+	case remote.HTTPS, remote.HTTP:
+		return a.httpMethod(ctx, ep)
+	case remote.SSH:
+		return a.sshMethod(ep)
+	default:
+		return nil, nil
+	}
+}
+
+func (a *Authenticator) httpMethod(ctx context.Context, ep *remote.Endpoint) (transport.AuthMethod, error) {
+	if ep.Password != "" {
+		return basicAuth(ep.User, ep.Password), nil
+	}
+
+	key := envKey(ep.Host)
+	if token, ok := a.OSWrap.LookupEnv(TokenEnvPrefix + key); ok && token != "" {
+		username, _ := a.OSWrap.LookupEnv(UsernameEnvPrefix + key)
+		return basicAuth(username, token), nil
+	}
+
+	host, err := a.lookupHost(ep.Host)
+	if err != nil {
+		return nil, err
+	}
+	if host != nil && host.Token != "" {
+		return basicAuth(host.Username, host.Token), nil
+	}
+
+	if a.Helper == nil {
+		return nil, nil
+	}
+
+	username, password, err := a.Helper.FillCredential(ctx, ep.Transport.String(), ep.Host, ep.Path)
+	if err != nil || password == "" {
+		// the helper is optional, so its absence or failure
+		// must not prevent fetching of public repositories
+		return nil, nil
+	}
+
+	return basicAuth(username, password), nil
+}
+
+func (a *Authenticator) sshMethod(ep *remote.Endpoint) (transport.AuthMethod, error) {
+	user := ep.User
+	if user == "" {
+		user = DefaultSSHUser
+	}
+
+	host, err := a.lookupHost(ep.Host)
+	if err != nil {
+		return nil, err
+	}
+	if host != nil && host.SSHKey != "" {
+		return a.publicKeys(user, host.SSHKey, host.Passphrase)
+	}
+
+	if keyFile, ok := a.OSWrap.LookupEnv("BPM_SSH_KEY"); ok && keyFile != "" {
+		passphrase, _ := a.OSWrap.LookupEnv("BPM_SSH_KEY_PASSPHRASE")
+		return a.publicKeys(user, keyFile, passphrase)
+	}
+
+	if sock, ok := a.OSWrap.LookupEnv("SSH_AUTH_SOCK"); ok && sock != "" {
+		method, err := ssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to ssh agent: %v", err)
+		}
+
+		return method, nil
+	}
+
+	homeDir, err := a.OSWrap.UserHomeDir()
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, name := range defaultKeyFiles {
+		keyFile := filepath.Join(homeDir, ".ssh", name)
+		if ok, _ := a.OSWrap.Exists(keyFile); ok {
+			return a.publicKeys(user, keyFile, "")
+		}
+	}
+
+	return nil, nil
+}
+
+func (a *Authenticator) publicKeys(user, keyFile, passphrase string) (transport.AuthMethod, error) {
+	keyFile, err := a.expandHome(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := ssh.NewPublicKeysFromFile(user, keyFile, passphrase)
+	if err != nil {
+		// the error of parsing a key never contains the passphrase
+		return nil, fmt.Errorf("failed to load ssh key %s: %v", keyFile, err)
+	}
+
+	return method, nil
+}
+
+func (a *Authenticator) expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	homeDir, err := a.OSWrap.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, path[2:]), nil
+}
+
+func (a *Authenticator) lookupHost(name string) (*HostCredentials, error) {
+	if a.CredentialsFile == "" {
+		return nil, nil
+	}
+
+	a.once.Do(func() {
+		a.creds, a.err = a.loadCredentialsFile()
+	})
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return a.creds.Host(name), nil
+}
+
+func (a *Authenticator) loadCredentialsFile() (*CredentialsFile, error) {
+	ok, err := a.OSWrap.Exists(a.CredentialsFile)
+	if err != nil || !ok {
+		return new(CredentialsFile), nil
+	}
+
+	content, err := a.OSWrap.ReadFile(a.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %v", err)
+	}
+
+	return DecodeCredentialsFile(content)
+}
+
+func basicAuth(username, password string) *http.BasicAuth {
+	if username == "" {
+		username = DefaultTokenUsername
+	}
+
+	return &http.BasicAuth{Username: username, Password: password}
+}
+
+// envKey converts the host name into the env variable
+// suffix, e.g. 'gitlab.example.com' => 'GITLAB_EXAMPLE_COM'
+func envKey(host string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, host)
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// CredentialsFile is the file with credentials of git hosts, e.g.:
+//
+//	host "gitlab.example.com" {
+//	  username = "oauth2"
+//	  token    = "..."
+//	}
+//
+//	host "git.corp.io" {
+//	  ssh_key = "~/.ssh/id_corp"
+//	}
+type CredentialsFile struct {
+	Hosts []*HostCredentials `hcl:"host,block"`
+}
+
+type HostCredentials struct {
+	Name       string `hcl:"name,label"`
+	Username   string `hcl:"username,optional"`
+	Token      string `hcl:"token,optional"`
+	SSHKey     string `hcl:"ssh_key,optional"`
+	Passphrase string `hcl:"passphrase,optional"`
+}
+
+func (f *CredentialsFile) Host(name string) *HostCredentials {
+	for _, h := range f.Hosts {
+		if h.Name == name {
+			return h
+		}
+	}
+
+	return nil
+}
+
+func DecodeCredentialsFile(content []byte) (*CredentialsFile, error) {
+	result := new(CredentialsFile)
+	if err := hclsimple.Decode(constant.CredentialsFileName, content, nil, result); err != nil {
+		// diagnostics only contain the position of the problem,
+		// but not the values, so that tokens are not exposed
+		return nil, fmt.Errorf("failed to decode credentials file: %v", err)
+	}
+
+	return result, nil
+}
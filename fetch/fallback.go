@@ -0,0 +1,103 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-version"
+)
+
+// ErrNotFound is returned by downloaders if the requested
+// bundle or version does not exist in the particular source
+var ErrNotFound = errors.New("not found")
+
+// DownloadStep is a single source of bundles in the fallback order
+type DownloadStep struct {
+	Name       string // name used in error messages, e.g. proxy url or 'direct'
+	Downloader fetcherGitHub
+	AnyError   bool // fall back to the next step on any error, not only if the bundle is not found
+}
+
+// FallbackDownloader tries to download the bundle from each step in order,
+// similarly to how GOPROXY list is handled. By default the next step is only
+// used if the bundle is not found by the previous one.
+type FallbackDownloader struct {
+	Steps []*DownloadStep
+}
+
+func (d *FallbackDownloader) Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error) {
+	if len(d.Steps) == 0 {
+		return nil, fmt.Errorf("bundle downloads are disabled")
+	}
+
+	var result *multierror.Error
+	for _, step := range d.Steps {
+		b, err := step.Downloader.Download(ctx, source, tag)
+		if err == nil {
+			return b, nil
+		}
+
+		if len(d.Steps) == 1 {
+			return nil, err
+		}
+
+		result = multierror.Append(result, fmt.Errorf("%s: %w", step.Name, err))
+		if !step.AnyError && !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+
+	return nil, unwrapSingle(result)
+}
+
+// VersionLister lists the versions of a source without downloading it
+type VersionLister interface {
+	ListVersions(ctx context.Context, source string) ([]*version.Version, error)
+}
+
+// ListVersions lists the versions using the steps in the same order
+// as bundles are downloaded, steps that cannot list versions are skipped
+func (d *FallbackDownloader) ListVersions(ctx context.Context, source string) ([]*version.Version, error) {
+	var result *multierror.Error
+	for _, step := range d.Steps {
+		lister, ok := step.Downloader.(VersionLister)
+		if !ok {
+			continue
+		}
+
+		versions, err := lister.ListVersions(ctx, source)
+		if err == nil {
+			return versions, nil
+		}
+
+		if len(d.Steps) == 1 {
+			return nil, err
+		}
+
+		result = multierror.Append(result, fmt.Errorf("%s: %w", step.Name, err))
+		if !step.AnyError && !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("listing versions of %s is not supported", source)
+	}
+
+	return nil, unwrapSingle(result)
+}
+
+// DisabledDownloader refuses to download anything, it is
+// used when downloads are turned off, e.g. 'BPM_PROXY=off'
+type DisabledDownloader struct{}
+
+func (DisabledDownloader) Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error) {
+	return nil, fmt.Errorf("downloading %s is disabled", source)
+}
+
+func (DisabledDownloader) ListVersions(ctx context.Context, source string) ([]*version.Version, error) {
+	return nil, fmt.Errorf("listing versions of %s is disabled", source)
+}
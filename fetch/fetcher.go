@@ -2,12 +2,18 @@ package fetch
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/4rchr4y/bpm/bundle"
 	"github.com/4rchr4y/bpm/bundleutil"
 	"github.com/4rchr4y/bpm/core"
-	"github.com/4rchr4y/godevkit/v3/regex"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-version"
 )
 
 type fetcherInspector interface {
@@ -25,11 +31,17 @@ type fetcherGitHub interface {
 	Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error)
 }
 
+type fetcherSources interface {
+	Resolve(source string) (*remote.Endpoint, error)
+}
+
 type Fetcher struct {
 	IO        core.IO
 	Storage   fetcherStorage
 	Inspector fetcherInspector
 	GitHub    fetcherGitHub
+	Sources   fetcherSources
+	Workers   int // maximum number of bundles fetched simultaneously
 }
 
 type FetchOutput struct {
@@ -58,42 +70,267 @@ func (fres *FetchOutput) Merge() []*bundle.Bundle {
 	return result
 }
 
+// CycleError is returned when bundles require each other directly or
+// through other bundles, since such requirements can never be satisfied
+type CycleError struct {
+	Path []string // chain of requirements, e.g. 'a@v1 -> b@v2 -> a@v1'
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("requirement cycle detected: %s", strings.Join(e.Path, " -> "))
+}
+
+// fetchSession holds the state of a single fetch run, so that
+// each bundle version is fetched and inspected only once
+type fetchSession struct {
+	group   *fetchGroup
+	mu      sync.Mutex
+	outputs map[string]*FetchOutput // fully fetched requirement trees by resolved `source@version`
+}
+
+func (d *Fetcher) newSession() *fetchSession {
+	return &fetchSession{
+		group:   newFetchGroup(d.Workers),
+		outputs: make(map[string]*FetchOutput),
+	}
+}
+
+func (s *fetchSession) output(key string) (*FetchOutput, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	output, exists := s.outputs[key]
+	return output, exists
+}
+
+func (s *fetchSession) storeOutput(key string, output *FetchOutput) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outputs[key] = output
+}
+
+// Fetch fetches the bundle and all of its transitive requirements.
+// Requirements of each bundle are fetched concurrently, but no more
+// than `Workers` bundles are being fetched at the same time.
 func (d *Fetcher) Fetch(ctx context.Context, source string, version *bundle.VersionSpec) (*FetchOutput, error) {
-	target, err := d.PlainFetch(ctx, source, version)
+	return d.fetch(ctx, d.newSession(), nil, source, version)
+}
+
+func (d *Fetcher) fetch(ctx context.Context, session *fetchSession, path []string, source string, version *bundle.VersionSpec) (*FetchOutput, error) {
+	target, err := session.group.do(ctx, source, version, func() (*bundle.Bundle, error) {
+		return d.PlainFetch(ctx, source, version)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch %s: %v", source, err)
+		return nil, fmt.Errorf("failed to fetch %s: %w", remote.Redact(source), err)
+	}
+
+	key := bundleKey(target)
+	for i := range path {
+		if path[i] == key {
+			cycle := make([]string, 0, len(path)-i+1)
+			cycle = append(cycle, path[i:]...)
+			return nil, &CycleError{Path: append(cycle, key)}
+		}
+	}
+
+	if output, exists := session.output(key); exists {
+		return output, nil
 	}
 
 	if target.BundleFile.Require == nil {
 		return &FetchOutput{Target: target}, nil
 	}
 
-	rindirect := make([]*bundle.Bundle, 0)
-	rdirect := make([]*bundle.Bundle, len(target.BundleFile.Require.List))
-	for i, r := range target.BundleFile.Require.List {
+	// copying the path to ensure that neighbouring
+	// branches do not share the underlying array
+	path = append(path[:len(path):len(path)], key)
+
+	requireList := target.BundleFile.Require.List
+	outputs := make([]*FetchOutput, len(requireList))
+
+	// versions are parsed before any fetch is started, so that an invalid
+	// requirement does not leave goroutines running after returning
+	versions := make([]*bundle.VersionSpec, len(requireList))
+	for i, r := range requireList {
 		v, err := bundle.ParseVersionExpr(r.Version)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid version of %s required by %s: %v", r.Source, key, err)
 		}
 
-		fetchOutput, err := d.Fetch(ctx, r.Source, v)
-		if err != nil {
-			return nil, err
+		versions[i] = v
+	}
+
+	// the first failed requirement cancels its siblings, since the bundle
+	// cannot be fetched anyway, the errors of the canceled ones are dropped
+	parentCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		result *multierror.Error
+	)
+
+	for i, r := range requireList {
+		wg.Add(1)
+		go func(i int, source string, version *bundle.VersionSpec) {
+			defer wg.Done()
+
+			output, err := d.fetch(ctx, session, path, source, version)
+			if err != nil {
+				mu.Lock()
+				result = multierror.Append(result, err)
+				mu.Unlock()
+
+				cancel()
+				return
+			}
+
+			outputs[i] = output
+		}(i, r.Source, versions[i])
+	}
+
+	wg.Wait()
+
+	if err := result.ErrorOrNil(); err != nil {
+		if parentCtx.Err() == nil {
+			result = dropCanceled(result)
 		}
 
-		rdirect[i] = fetchOutput.Target
-		rindirect = append(rindirect, fetchOutput.Rdirect...)
+		return nil, unwrapSingle(result)
+	}
+
+	seen := map[string]struct{}{key: {}}
+	rindirect := make([]*bundle.Bundle, 0)
+	rdirect := make([]*bundle.Bundle, len(requireList))
+	for i, output := range outputs {
+		rdirect[i] = output.Target
+		seen[bundleKey(output.Target)] = struct{}{}
+
+		rindirect = append(rindirect, output.Rdirect...)
+		rindirect = append(rindirect, output.Rindirect...)
 	}
 
-	return &FetchOutput{
+	output := &FetchOutput{
 		Target:    target,
 		Rdirect:   rdirect,
-		Rindirect: rindirect,
-	}, nil
+		Rindirect: uniqueBundles(rindirect, seen),
+	}
+
+	session.storeOutput(key, output)
+	return output, nil
+}
+
+// FetchRequest describes a single bundle that needs to be fetched
+type FetchRequest struct {
+	Source  string
+	Version *bundle.VersionSpec
+}
+
+// FetchAll fetches the requested bundles without their requirements.
+// Bundles are fetched concurrently, no more than `Workers` at the same
+// time, and the result is returned in the same order as requested.
+// Errors of all failed fetches are aggregated into a single error.
+func (d *Fetcher) FetchAll(ctx context.Context, requests []*FetchRequest) ([]*bundle.Bundle, error) {
+	group := newFetchGroup(d.Workers)
+	bundles := make([]*bundle.Bundle, len(requests))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		result *multierror.Error
+	)
+
+	for i, r := range requests {
+		wg.Add(1)
+		go func(i int, r *FetchRequest) {
+			defer wg.Done()
+
+			b, err := group.do(ctx, r.Source, r.Version, func() (*bundle.Bundle, error) {
+				return d.PlainFetch(ctx, r.Source, r.Version)
+			})
+			if err != nil {
+				mu.Lock()
+				result = multierror.Append(result, fmt.Errorf("failed to fetch %s: %w",
+					bundleutil.FormatSourceWithVersion(remote.Redact(r.Source), r.Version.String()), err,
+				))
+				mu.Unlock()
+				return
+			}
+
+			bundles[i] = b
+		}(i, r)
+	}
+
+	wg.Wait()
+
+	if err := result.ErrorOrNil(); err != nil {
+		return nil, unwrapSingle(result)
+	}
+
+	return bundles, nil
+}
+
+// dropCanceled removes the errors caused by the cancellation of the
+// context, unless there are no other errors
+func dropCanceled(err *multierror.Error) *multierror.Error {
+	result := &multierror.Error{ErrorFormat: err.ErrorFormat}
+	for _, e := range err.Errors {
+		if !errors.Is(e, context.Canceled) {
+			result.Errors = append(result.Errors, e)
+		}
+	}
+
+	if len(result.Errors) == 0 {
+		return err
+	}
+
+	return result
+}
+
+// unwrapSingle returns the only error as is, so that its type
+// remains available to the caller, e.g. a cycle error
+func unwrapSingle(err *multierror.Error) error {
+	if len(err.Errors) == 1 {
+		return err.Errors[0]
+	}
+
+	sort.Slice(err.Errors, func(i, j int) bool {
+		return err.Errors[i].Error() < err.Errors[j].Error()
+	})
+
+	return err
+}
+
+// uniqueBundles removes duplicates and bundles that are already known
+func uniqueBundles(list []*bundle.Bundle, seen map[string]struct{}) []*bundle.Bundle {
+	result := make([]*bundle.Bundle, 0, len(list))
+	for _, b := range list {
+		key := bundleKey(b)
+		if _, exists := seen[key]; exists {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		result = append(result, b)
+	}
+
+	return result
+}
+
+func bundleKey(b *bundle.Bundle) string {
+	return bundleutil.FormatSourceWithVersion(b.Repository(), b.Version.String())
 }
 
 func (f *Fetcher) PlainFetch(ctx context.Context, source string, version *bundle.VersionSpec) (*bundle.Bundle, error) {
-	if !regex.UrlPattern.MatchString(source) {
+	endpoint, err := f.Sources.Resolve(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.IsLocal() {
 		b, err := f.Storage.LoadFromAbs(source, version)
 		if err != nil {
 			return nil, err
@@ -118,7 +355,33 @@ func (f *Fetcher) PlainFetch(ctx context.Context, source string, version *bundle
 	return b, nil
 }
 
+// ListVersions returns the tagged versions of the source in ascending order,
+// the versions are listed remotely, even if the source is stored locally
+func (f *Fetcher) ListVersions(ctx context.Context, source string) ([]*version.Version, error) {
+	endpoint, err := f.Sources.Resolve(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.IsLocal() {
+		return nil, fmt.Errorf("local bundle %s has no versions", source)
+	}
+
+	lister, ok := f.GitHub.(VersionLister)
+	if !ok {
+		return nil, fmt.Errorf("listing versions of %s is not supported", remote.Redact(source))
+	}
+
+	return lister.ListVersions(ctx, source)
+}
+
 func (f *Fetcher) FetchLocal(ctx context.Context, source string, version *bundle.VersionSpec) (*bundle.Bundle, error) {
+	if version.IsConstraint() {
+		// a constraint must be resolved into a concrete
+		// version first, which is only possible remotely
+		return nil, nil
+	}
+
 	ok := f.Storage.Some(source, version.String())
 	if !ok {
 		return nil, nil
@@ -128,7 +391,7 @@ func (f *Fetcher) FetchLocal(ctx context.Context, source string, version *bundle
 	if err != nil {
 		f.IO.PrintfErr(
 			"failed to load bundle %s from local storage: %v",
-			bundleutil.FormatSourceWithVersion(source, version.String()),
+			bundleutil.FormatSourceWithVersion(remote.Redact(source), version.String()),
 			err,
 		)
 		return nil, err
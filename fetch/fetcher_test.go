@@ -0,0 +1,240 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) Store(b *bundle.Bundle) error            { return nil }
+func (fakeStorage) Some(source string, version string) bool { return false }
+func (fakeStorage) Load(source string, version *bundle.VersionSpec) (*bundle.Bundle, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (fakeStorage) LoadFromAbs(source string, v *bundle.VersionSpec) (*bundle.Bundle, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+type fakeInspector struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (insp *fakeInspector) Inspect(b *bundle.Bundle) error {
+	insp.mu.Lock()
+	defer insp.mu.Unlock()
+
+	insp.calls[b.Repository()+"@"+b.Version.String()]++
+	return nil
+}
+
+type fakeGitHub struct {
+	mu       sync.Mutex
+	bundles  map[string]*bundle.Bundle
+	calls    map[string]int
+	blocking map[string]struct{}        // sources whose downloads last until the context is done
+	barriers map[string]*sync.WaitGroup // sources whose downloads wait for each other before returning
+}
+
+func (gh *fakeGitHub) Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error) {
+	if _, blocks := gh.blocking[source]; blocks {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	if barrier, exists := gh.barriers[source]; exists {
+		barrier.Done()
+		barrier.Wait()
+	}
+
+	gh.mu.Lock()
+	defer gh.mu.Unlock()
+
+	key := source + "@" + tag.String()
+	gh.calls[key]++
+
+	b, exists := gh.bundles[key]
+	if !exists {
+		return nil, fmt.Errorf("version '%s' is not found", tag.String())
+	}
+
+	return b, nil
+}
+
+// add registers a bundle with requirements in the form of 'source@version'
+func (gh *fakeGitHub) add(key string, requires ...string) {
+	source, version, _ := strings.Cut(key, "@")
+	v, err := bundle.ParseVersionExpr(version)
+	if err != nil {
+		panic(err)
+	}
+
+	requireList := make([]*bundlefile.RequirementDecl, 0, len(requires))
+	for _, r := range requires {
+		s, version, _ := strings.Cut(r, "@")
+		requireList = append(requireList, &bundlefile.RequirementDecl{
+			Source:  s,
+			Name:    path.Base(s),
+			Version: version,
+		})
+	}
+
+	gh.bundles[key] = &bundle.Bundle{
+		Source:  source,
+		Version: v,
+		BundleFile: &bundlefile.Schema{
+			Package: &bundlefile.PackageBlock{Name: path.Base(source), Repository: source},
+			Require: &bundlefile.RequireBlock{List: requireList},
+		},
+		LockFile: lockfile.PrepareSchema(nil),
+	}
+}
+
+func newTestFetcher() (*Fetcher, *fakeGitHub, *fakeInspector) {
+	gh := &fakeGitHub{
+		bundles:  make(map[string]*bundle.Bundle),
+		calls:    make(map[string]int),
+		blocking: make(map[string]struct{}),
+		barriers: make(map[string]*sync.WaitGroup),
+	}
+	inspector := &fakeInspector{calls: make(map[string]int)}
+
+	return &Fetcher{
+		IO:        iostream.NewIOStream(iostream.WithOutput(io.Discard), iostream.WithErrOutput(io.Discard)),
+		Storage:   fakeStorage{},
+		Inspector: inspector,
+		GitHub:    gh,
+		Sources:   &remote.Resolver{},
+	}, gh, inspector
+}
+
+func mustParseVersion(t *testing.T, version string) *bundle.VersionSpec {
+	v, err := bundle.ParseVersionExpr(version)
+	require.NoError(t, err)
+	return v
+}
+
+func TestFetch(t *testing.T) {
+	t.Run("Requirement cycle should be reported with the full path", func(t *testing.T) {
+		fetcher, gh, _ := newTestFetcher()
+		gh.add("github.com/x/a@v1.0.0", "github.com/x/b@v2.0.0")
+		gh.add("github.com/x/b@v2.0.0", "github.com/x/c@v1.0.0")
+		gh.add("github.com/x/c@v1.0.0", "github.com/x/a@v1.0.0")
+
+		_, err := fetcher.Fetch(context.Background(), "github.com/x/a", mustParseVersion(t, "v1.0.0"))
+		require.Error(t, err)
+
+		var cycleErr *CycleError
+		require.ErrorAs(t, err, &cycleErr)
+		require.Equal(t, []string{
+			"github.com/x/a@v1.0.0",
+			"github.com/x/b@v2.0.0",
+			"github.com/x/c@v1.0.0",
+			"github.com/x/a@v1.0.0",
+		}, cycleErr.Path)
+	})
+
+	t.Run("Bundle required twice in a diamond should be fetched and inspected once", func(t *testing.T) {
+		fetcher, gh, inspector := newTestFetcher()
+		gh.add("github.com/x/a@v1.0.0", "github.com/x/b@v1.0.0", "github.com/x/c@v1.0.0")
+		gh.add("github.com/x/b@v1.0.0", "github.com/x/d@v1.0.0")
+		gh.add("github.com/x/c@v1.0.0", "github.com/x/d@v1.0.0")
+		gh.add("github.com/x/d@v1.0.0", "github.com/x/e@v1.0.0")
+		gh.add("github.com/x/e@v1.0.0")
+
+		output, err := fetcher.Fetch(context.Background(), "github.com/x/a", mustParseVersion(t, "v1.0.0"))
+		require.NoError(t, err)
+		require.Equal(t, 1, gh.calls["github.com/x/d@v1.0.0"])
+		require.Equal(t, 1, inspector.calls["github.com/x/d@v1.0.0"])
+		require.Len(t, output.Rdirect, 2)
+		require.Len(t, output.Rindirect, 2, "indirect requirements should be unique and include all levels")
+	})
+
+	t.Run("Errors of all failed requirements should be aggregated", func(t *testing.T) {
+		fetcher, gh, _ := newTestFetcher()
+		gh.add("github.com/x/a@v1.0.0", "github.com/x/b@v1.0.0", "github.com/x/c@v1.0.0")
+
+		// both requirements fail before either of them cancels the other
+		barrier := new(sync.WaitGroup)
+		barrier.Add(2)
+		gh.barriers["github.com/x/b"] = barrier
+		gh.barriers["github.com/x/c"] = barrier
+
+		_, err := fetcher.Fetch(context.Background(), "github.com/x/a", mustParseVersion(t, "v1.0.0"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "2 errors occurred")
+		require.Contains(t, err.Error(), "failed to fetch github.com/x/b: version 'v1.0.0' is not found")
+		require.Contains(t, err.Error(), "failed to fetch github.com/x/c: version 'v1.0.0' is not found")
+		require.NotContains(t, err.Error(), context.Canceled.Error())
+	})
+
+	t.Run("Cycle should be reported alone when its siblings are canceled", func(t *testing.T) {
+		fetcher, gh, _ := newTestFetcher()
+		gh.add("github.com/x/a@v1.0.0", "github.com/x/a@v1.0.0", "github.com/x/b@v1.0.0")
+		gh.add("github.com/x/b@v1.0.0")
+		gh.blocking["github.com/x/b"] = struct{}{}
+
+		_, err := fetcher.Fetch(context.Background(), "github.com/x/a", mustParseVersion(t, "v1.0.0"))
+
+		var cycleErr *CycleError
+		require.ErrorAs(t, err, &cycleErr)
+		require.NotContains(t, err.Error(), context.Canceled.Error())
+	})
+
+	t.Run("Canceled fetch should not be remembered", func(t *testing.T) {
+		fetcher, gh, _ := newTestFetcher()
+		gh.add("github.com/x/b@v1.0.0")
+
+		session := fetcher.newSession()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := fetcher.fetch(ctx, session, nil, "github.com/x/b", mustParseVersion(t, "v1.0.0"))
+		require.ErrorIs(t, err, context.Canceled)
+
+		output, err := fetcher.fetch(context.Background(), session, nil, "github.com/x/b", mustParseVersion(t, "v1.0.0"))
+		require.NoError(t, err)
+		require.Equal(t, "v1.0.0", output.Target.Version.String())
+	})
+
+	t.Run("Failed requirement should cancel fetching of its siblings", func(t *testing.T) {
+		fetcher, gh, _ := newTestFetcher()
+		gh.add("github.com/x/a@v1.0.0", "github.com/x/b@v1.0.0", "github.com/x/c@v1.0.0")
+		gh.blocking["github.com/x/c"] = struct{}{}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, err := fetcher.Fetch(ctx, "github.com/x/a", mustParseVersion(t, "v1.0.0"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch github.com/x/b")
+		require.NoError(t, ctx.Err(), "fetch should not wait for the blocked sibling")
+	})
+
+	t.Run("Canceled context should stop fetching", func(t *testing.T) {
+		fetcher, gh, _ := newTestFetcher()
+		gh.add("github.com/x/a@v1.0.0")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := fetcher.FetchAll(ctx, []*FetchRequest{
+			{Source: "github.com/x/a", Version: mustParseVersion(t, "v1.0.0")},
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
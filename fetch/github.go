@@ -3,6 +3,7 @@ package fetch
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/4rchr4y/bpm/bundle"
@@ -12,10 +13,12 @@ import (
 	"github.com/4rchr4y/bpm/bundleutil/encode"
 	"github.com/4rchr4y/bpm/constant"
 	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/fetch/remote"
 	"github.com/4rchr4y/bpm/regoutil"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/hashicorp/go-version"
 )
 
@@ -28,19 +31,49 @@ type githubFetcherEncoder interface {
 
 type githubFetcherClient interface {
 	CloneWithContext(ctx context.Context, opts *git.CloneOptions) (*git.Repository, error)
+	ListWithContext(ctx context.Context, url string, auth transport.AuthMethod) ([]*plumbing.Reference, error)
+}
+
+type githubFetcherSources interface {
+	Resolve(source string) (*remote.Endpoint, error)
+}
+
+type githubFetcherAuth interface {
+	Method(ctx context.Context, ep *remote.Endpoint) (transport.AuthMethod, error)
 }
 
 type GithubFetcher struct {
 	IO      core.IO
 	Client  githubFetcherClient
 	Encoder githubFetcherEncoder
+	Sources githubFetcherSources
+	Auth    githubFetcherAuth
 }
 
 func (gh *GithubFetcher) Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error) {
-	gh.IO.PrintfInfo("downloading %s", bundleutil.FormatSourceWithVersion(source, tag.String()))
+	gh.IO.PrintfInfo("downloading %s", bundleutil.FormatSourceWithVersion(remote.Redact(source), tag.String()))
+
+	endpoint, err := gh.Sources.Resolve(source)
+	if err != nil {
+		return nil, err
+	}
 
 	options := &git.CloneOptions{
-		URL: fmt.Sprintf("https://%s.git", source),
+		URL: endpoint.URL,
+	}
+
+	if tag != nil && !tag.IsConstraint() && !tag.IsPseudo() {
+		// only the tagged commit is needed to get an exact version
+		options.ReferenceName = plumbing.NewTagReferenceName(tag.SemTag.Original())
+		options.SingleBranch = true
+		options.Depth = 1
+	}
+
+	if gh.Auth != nil {
+		options.Auth, err = gh.Auth.Method(ctx, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate to %s: %v", endpoint.Host, err)
+		}
 	}
 
 	repo, err := gh.Client.CloneWithContext(ctx, options)
@@ -65,7 +98,7 @@ func (gh *GithubFetcher) Download(ctx context.Context, source string, tag *bundl
 
 	return &bundle.Bundle{
 		Version:    v,
-		Source:     source,
+		Source:     remote.Redact(source),
 		BundleFile: bundlefile.PrepareSchema(filesOutput.BundleFile),
 		LockFile:   lockfile.PrepareSchema(filesOutput.LockFile),
 		RegoFiles:  fileifyOutput.RegoFiles,
@@ -74,6 +107,31 @@ func (gh *GithubFetcher) Download(ctx context.Context, source string, tag *bundl
 	}, nil
 }
 
+// ListVersions returns the semantic versions of the source tags in ascending
+// order. Only the references of the remote repository are requested, so
+// neither the repository nor the bundle is downloaded.
+func (gh *GithubFetcher) ListVersions(ctx context.Context, source string) ([]*version.Version, error) {
+	endpoint, err := gh.Sources.Resolve(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth transport.AuthMethod
+	if gh.Auth != nil {
+		auth, err = gh.Auth.Method(ctx, endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate to %s: %v", endpoint.Host, err)
+		}
+	}
+
+	refs, err := gh.Client.ListWithContext(ctx, endpoint.URL, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %v", remote.Redact(source), err)
+	}
+
+	return sortedVersions(collectTags(refs)), nil
+}
+
 type getFilesOutput struct {
 	FileSet    map[string][]byte
 	BundleFile *bundlefile.Schema
@@ -169,6 +227,10 @@ func (gh *GithubFetcher) fetchCommitByTag(repo *git.Repository, v *bundle.Versio
 		return gh.getLatestVersionCommit(repo)
 	}
 
+	if v.IsConstraint() {
+		return gh.getConstraintVersionCommit(repo, v.Constraint)
+	}
+
 	if v.IsPseudo() {
 		commit, err := gh.getPseudoVersionCommit(repo, v)
 		if err != nil {
@@ -242,32 +304,72 @@ func (gh *GithubFetcher) getLatestVersionCommit(repo *git.Repository) (*object.C
 	return commit, bundle.NewVersionSpecFromCommit(commit, v), nil
 }
 
+// getConstraintVersionCommit picks the highest tag that satisfies the given constraint
+func (gh *GithubFetcher) getConstraintVersionCommit(repo *git.Repository, c *bundle.VersionConstraint) (*object.Commit, *bundle.VersionSpec, error) {
+	tags, err := gh.collectTagList(repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, ref := findLatestMatchingVersion(tags, c)
+	if v == nil || ref == nil {
+		return nil, nil, fmt.Errorf("no version matching '%s' is found", c.Expr)
+	}
+
+	commit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return commit, bundle.NewVersionSpecFromCommit(commit, v), nil
+}
+
 func (gh *GithubFetcher) collectTagList(repo *git.Repository) (map[*version.Version]*plumbing.Reference, error) {
 	iter, err := repo.Tags()
 	if err != nil {
 		return nil, err
 	}
 
-	tags := make(map[*version.Version]*plumbing.Reference)
+	refs := make([]*plumbing.Reference, 0)
 	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return collectTags(refs), nil
+}
+
+// collectTags maps the semantic versions of the tags to their
+// references, other references and tags are skipped
+func collectTags(refs []*plumbing.Reference) map[*version.Version]*plumbing.Reference {
+	tags := make(map[*version.Version]*plumbing.Reference)
+	for _, ref := range refs {
 		if !ref.Name().IsTag() {
-			return nil
+			continue
 		}
 
 		v, err := version.NewVersion(ref.Name().Short())
 		if err != nil {
-			return nil
+			continue
 		}
 
 		tags[v] = ref
+	}
 
-		return nil
-	})
-	if err != nil {
-		return nil, err
+	return tags
+}
+
+func sortedVersions(tags map[*version.Version]*plumbing.Reference) []*version.Version {
+	result := make([]*version.Version, 0, len(tags))
+	for v := range tags {
+		result = append(result, v)
 	}
 
-	return tags, nil
+	sort.Sort(version.Collection(result))
+	return result
 }
 
 func (gh *GithubFetcher) getCurrentVersionCommit(repo *git.Repository, tag string) (*object.Commit, error) {
@@ -312,6 +414,21 @@ func mustFindLatestVersion(tags map[*version.Version]*plumbing.Reference) (v *ve
 	return v, ref
 }
 
+func findLatestMatchingVersion(tags map[*version.Version]*plumbing.Reference, c *bundle.VersionConstraint) (v *version.Version, ref *plumbing.Reference) {
+	for version, reference := range tags {
+		if !c.Check(version) {
+			continue
+		}
+
+		if v == nil || version.GreaterThan(v) {
+			v = version
+			ref = reference
+		}
+	}
+
+	return v, ref
+}
+
 func getLatestCommit(repo *git.Repository) (*object.Commit, error) {
 	ref, err := repo.Head()
 	if err != nil {
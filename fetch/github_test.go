@@ -0,0 +1,175 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/4rchr4y/bpm/internal/service/github"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+const testBundleFile = `package {
+  name       = "policy"
+  repository = "git.example.com/team/policy"
+}
+`
+
+const testRegoFile = `package policy
+
+allow := true
+`
+
+// newBareRepository creates a bare repository at the given path with
+// a single commit that contains a bundle, the commit gets all given tags
+func newBareRepository(t *testing.T, path string, tags ...string) {
+	worktreeDir := t.TempDir()
+
+	repo, err := git.PlainInit(worktreeDir, false)
+	require.NoError(t, err)
+
+	files := map[string]string{
+		constant.BundleFileName: testBundleFile,
+		"policy.rego":           testRegoFile,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(worktreeDir, name), []byte(content), 0644))
+	}
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, worktree.AddGlob("."))
+
+	hash, err := worktree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	for _, tag := range tags {
+		_, err = repo.CreateTag(tag, hash, nil)
+		require.NoError(t, err)
+	}
+
+	_, err = git.PlainClone(path, true, &git.CloneOptions{URL: worktreeDir})
+	require.NoError(t, err)
+}
+
+func newTestGithubFetcher(cacheDir string, rules []*remote.Rule) *GithubFetcher {
+	return &GithubFetcher{
+		IO:      iostream.NewIOStream(iostream.WithOutput(io.Discard), iostream.WithErrOutput(io.Discard)),
+		Client:  &github.GitClient{CacheDir: cacheDir},
+		Encoder: &encode.Encoder{},
+		Sources: &remote.Resolver{Rules: rules},
+	}
+}
+
+func TestGithubFetcherDownload(t *testing.T) {
+	hostDir := t.TempDir()
+	repoDir := filepath.Join(hostDir, "team", "policy.git")
+	newBareRepository(t, repoDir, "v1.0.0")
+
+	rules, err := remote.ParseRules("git.example.com=file://" + filepath.ToSlash(hostDir))
+	require.NoError(t, err)
+
+	fetcher := newTestGithubFetcher("", rules)
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "Host rule", source: "git.example.com/team/policy"},
+		{name: "File URL", source: "file://" + filepath.ToSlash(repoDir)},
+		{name: "Bare repository path", source: repoDir},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := fetcher.Download(context.Background(), tt.source, mustParseVersion(t, "v1.0.0"))
+			require.NoError(t, err)
+			require.Equal(t, "v1.0.0", b.Version.String())
+			require.Equal(t, "git.example.com/team/policy", b.Repository())
+			require.Contains(t, b.RegoFiles, "policy.rego")
+		})
+	}
+}
+
+func TestGithubFetcherDownloadCached(t *testing.T) {
+	repoDir := filepath.Join(t.TempDir(), "policy.git")
+	newBareRepository(t, repoDir, "v1.0.0")
+
+	cacheDir := t.TempDir()
+	fetcher := newTestGithubFetcher(cacheDir, nil)
+
+	t.Run("Exact version should be fetched into the mirror", func(t *testing.T) {
+		b, err := fetcher.Download(context.Background(), repoDir, mustParseVersion(t, "v1.0.0"))
+		require.NoError(t, err)
+		require.Equal(t, "v1.0.0", b.Version.String())
+
+		entries, err := os.ReadDir(cacheDir)
+		require.NoError(t, err)
+		require.NotEmpty(t, entries)
+	})
+
+	t.Run("Exact version missing in the mirror should be fetched into it", func(t *testing.T) {
+		repo, err := git.PlainOpen(repoDir)
+		require.NoError(t, err)
+
+		head, err := repo.Head()
+		require.NoError(t, err)
+
+		_, err = repo.CreateTag("v1.1.0", head.Hash(), nil)
+		require.NoError(t, err)
+
+		b, err := fetcher.Download(context.Background(), repoDir, mustParseVersion(t, "v1.1.0"))
+		require.NoError(t, err)
+		require.Equal(t, "v1.1.0", b.Version.String())
+	})
+
+	t.Run("Latest version should be fetched into the mirror and updated later", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			b, err := fetcher.Download(context.Background(), repoDir, nil)
+			require.NoError(t, err)
+			require.Equal(t, "v1.1.0", b.Version.String())
+		}
+	})
+
+	t.Run("Exact versions should be served from the mirror", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(repoDir))
+
+		for _, tag := range []string{"v1.0.0", "v1.1.0"} {
+			b, err := fetcher.Download(context.Background(), repoDir, mustParseVersion(t, tag))
+			require.NoError(t, err)
+			require.Equal(t, tag, b.Version.String())
+		}
+	})
+}
+
+func TestGithubFetcherListVersions(t *testing.T) {
+	repoDir := filepath.Join(t.TempDir(), "policy.git")
+	newBareRepository(t, repoDir, "v1.9.0", "v1.10.0", "v2.0.0-rc.1", "v1.0.0", "not-a-version")
+
+	cacheDir := t.TempDir()
+	fetcher := newTestGithubFetcher(cacheDir, nil)
+
+	versions, err := fetcher.ListVersions(context.Background(), repoDir)
+	require.NoError(t, err)
+
+	result := make([]string, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, v.Original())
+	}
+	require.Equal(t, []string{"v1.0.0", "v1.9.0", "v1.10.0", "v2.0.0-rc.1"}, result)
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "listing versions should not clone the repository")
+}
@@ -0,0 +1,103 @@
+package fetch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundleutil"
+)
+
+// DefaultWorkers is the number of bundles fetched simultaneously
+// if the limit is not specified explicitly
+const DefaultWorkers = 4
+
+// fetchGroup limits the number of simultaneous fetches and deduplicates
+// requests for the same `source@version`, so that concurrent callers wait
+// for the result of the first call instead of fetching the bundle again.
+// Results of completed calls are kept until the group is discarded.
+type fetchGroup struct {
+	sem   chan struct{}
+	mu    sync.Mutex
+	calls map[string]*fetchCall
+}
+
+type fetchCall struct {
+	done     chan struct{}
+	bundle   *bundle.Bundle
+	err      error
+	canceled bool // the call was interrupted, its result is not remembered
+}
+
+func newFetchGroup(workers int) *fetchGroup {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	return &fetchGroup{
+		sem:   make(chan struct{}, workers),
+		calls: make(map[string]*fetchCall),
+	}
+}
+
+// do executes fn only once for the given source and version. The result is
+// also registered under the concrete version of the fetched bundle, so that
+// later requests made with that version do not cause it to be refetched.
+// Calls interrupted by the cancellation of their context are not remembered,
+// callers waiting for such a call with a live context retry it instead.
+func (g *fetchGroup) do(ctx context.Context, source string, version *bundle.VersionSpec, fn func() (*bundle.Bundle, error)) (*bundle.Bundle, error) {
+	key := bundleutil.FormatSourceWithVersion(source, version.String())
+
+	g.mu.Lock()
+	if c, exists := g.calls[key]; exists {
+		g.mu.Unlock()
+
+		b, err := c.wait(ctx)
+		if ctx.Err() == nil && c.canceled {
+			return g.do(ctx, source, version, fn)
+		}
+
+		return b, err
+	}
+
+	c := &fetchCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer close(c.done)
+
+	select {
+	case g.sem <- struct{}{}:
+		if c.err = ctx.Err(); c.err == nil {
+			c.bundle, c.err = fn()
+		}
+		<-g.sem
+	case <-ctx.Done():
+		c.err = ctx.Err()
+	}
+
+	g.mu.Lock()
+	switch {
+	case c.err != nil && ctx.Err() != nil:
+		c.canceled = true
+		delete(g.calls, key)
+	case c.err == nil:
+		if resolved := bundleutil.FormatSourceWithVersion(source, c.bundle.Version.String()); resolved != key {
+			if _, exists := g.calls[resolved]; !exists {
+				g.calls[resolved] = c
+			}
+		}
+	}
+	g.mu.Unlock()
+
+	return c.bundle, c.err
+}
+
+func (c *fetchCall) wait(ctx context.Context) (*bundle.Bundle, error) {
+	select {
+	case <-c.done:
+		return c.bundle, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -1,7 +1,9 @@
 package github
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -36,3 +38,44 @@ func getGitUserInfo(key string) (string, error) {
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// FillCredential asks git credential helpers for the credentials of
+// the given repository. Nothing is returned if no helper is configured,
+// and helpers are never allowed to prompt the user in the terminal.
+func (cli *GitCLI) FillCredential(ctx context.Context, protocol, host, path string) (username, password string, err error) {
+	if helper, err := getGitConfig("credential.helper"); err != nil || helper == "" {
+		return "", "", nil
+	}
+
+	input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n\n", protocol, host, path)
+
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("git credential helper failed for %s", host)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+
+	return username, password, nil
+}
+
+func getGitConfig(key string) (string, error) {
+	cmd := exec.Command("git", "config", "--get", key)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
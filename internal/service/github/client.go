@@ -2,18 +2,179 @@ package github
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
 )
 
-type GitClient struct{}
+// GitClient clones repositories either into memory or, if `CacheDir` is
+// specified, into bare mirrors that are kept on disk and updated
+// incrementally on subsequent clones of the same repository.
+type GitClient struct {
+	CacheDir string
 
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // locks of the mirror directories
+}
+
+// CloneWithContext clones the repository. Shallow clones (`Depth` > 0) of
+// a single reference are served from the mirror as well, the reference is
+// fetched into the mirror only if the mirror does not contain it yet.
 func (client *GitClient) CloneWithContext(ctx context.Context, opts *git.CloneOptions) (*git.Repository, error) {
-	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, opts)
+	if client.CacheDir == "" {
+		return git.CloneContext(ctx, memory.NewStorage(), nil, opts)
+	}
+
+	dir := filepath.Join(client.CacheDir, mirrorDirName(opts.URL))
+
+	unlock := client.lock(dir)
+	defer unlock()
+
+	repo, err := client.openMirror(dir, opts.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchOpts := &git.FetchOptions{
+		Auth:  opts.Auth,
+		Tags:  git.AllTags,
+		Force: true,
+	}
+
+	if opts.Depth > 0 && opts.ReferenceName != "" {
+		if hasReference(repo, opts.ReferenceName) {
+			return repo, nil
+		}
+
+		// only the requested reference is fetched, but with its whole
+		// history, so that the mirror can be updated incrementally later
+		fetchOpts.RefSpecs = []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:%s", opts.ReferenceName, opts.ReferenceName)),
+		}
+		fetchOpts.Tags = git.NoTags
+	}
+
+	err = repo.FetchContext(ctx, fetchOpts)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to update mirror of %s: %v", opts.URL, err)
+	}
+
+	return repo, nil
+}
+
+// ListWithContext lists the references of the remote repository the same
+// way as 'git ls-remote' does, without cloning or fetching any objects
+func (client *GitClient) ListWithContext(ctx context.Context, url string, auth transport.AuthMethod) ([]*plumbing.Reference, error) {
+	r := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+
+	return r.ListContext(ctx, &git.ListOptions{Auth: auth})
+}
+
+// openMirror opens the mirror, or creates an empty one if it does not exist.
+// The mirror is initialized in a temporary directory first, so that an
+// interrupted initialization never leaves a broken mirror in the cache.
+func (client *GitClient) openMirror(dir string, url string) (*git.Repository, error) {
+	repo, err := git.PlainOpen(dir)
+	if err == nil || !errors.Is(err, git.ErrRepositoryNotExists) {
+		return repo, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), filepath.Base(dir)+".tmp-")
 	if err != nil {
 		return nil, err
 	}
+	defer os.RemoveAll(tmpDir)
+
+	repo, err = git.PlainInit(tmpDir, true)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name:   git.DefaultRemoteName,
+		URLs:   []string{url},
+		Mirror: true,
+		Fetch:  []config.RefSpec{"+refs/*:refs/*"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmpDir, dir); err != nil {
+		// another process could have created the mirror in the meantime
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return nil, err
+		}
+	}
+
+	return git.PlainOpen(dir)
+}
+
+func (client *GitClient) lock(dir string) func() {
+	client.mu.Lock()
+	if client.locks == nil {
+		client.locks = make(map[string]*sync.Mutex)
+	}
+
+	l, exists := client.locks[dir]
+	if !exists {
+		l = new(sync.Mutex)
+		client.locks[dir] = l
+	}
+	client.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+func hasReference(repo *git.Repository, name plumbing.ReferenceName) bool {
+	if name == "" {
+		return false
+	}
+
+	_, err := repo.Reference(name, false)
+	return err == nil
+}
+
+// mirrorDirName converts the clone URL into a directory name,
+// e.g. 'https://github.com/4rchr4y/example.git' => 'github.com/4rchr4y/example.git'
+func mirrorDirName(url string) string {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+	}
+
+	if at := strings.Index(url, "@"); at >= 0 && at < strings.IndexAny(url+"/", "/:") {
+		url = url[at+1:] // user name is not a part of the repository identity
+	}
+
+	url = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '.', r == '-', r == '_', r == '/':
+			return r
+		default:
+			return '_'
+		}
+	}, url)
 
-	return repo, err
+	// cleaning the path from '..' elements, so that the
+	// mirror can never end up outside of the cache directory
+	return strings.TrimPrefix(filepath.Clean("/"+url), "/")
 }
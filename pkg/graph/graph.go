@@ -0,0 +1,311 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundle/regofile"
+	"github.com/4rchr4y/bpm/bundleutil"
+)
+
+// Graph describes the requirements between bundles and the imports
+// between their modules, as they are recorded in the lock files
+type Graph struct {
+	Root        string        `json:"root"` // id of the root bundle node
+	Bundles     []*BundleNode `json:"bundles"`
+	BundleEdges []*Edge       `json:"bundleEdges"`
+	Modules     []*ModuleNode `json:"modules"`
+	ModuleEdges []*Edge       `json:"moduleEdges"`
+}
+
+type BundleNode struct {
+	ID        string `json:"id"` // 'source@version', or the repository of the root bundle
+	Source    string `json:"source"`
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Direction string `json:"direction,omitempty"` // requirement direction, empty for the root bundle
+}
+
+type ModuleNode struct {
+	Package    string `json:"package"` // e.g. 'example.rules.main'
+	Bundle     string `json:"bundle"`  // id of the bundle the module belongs to
+	Visibility string `json:"visibility,omitempty"`
+	File       string `json:"file,omitempty"`
+}
+
+// Edge connects two bundles by their ids or two modules by their packages,
+// edges between modules point to the import declaration
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// LockFileLoader loads the lock file of the required bundle version
+type LockFileLoader func(source, version string) (*lockfile.Schema, error)
+
+type BuildInput struct {
+	Name       string           // root bundle name
+	Repository string           // root bundle repository
+	LockFile   *lockfile.Schema // root bundle lock file
+	Load       LockFileLoader
+}
+
+type graphStorage interface {
+	Some(source string, version string) bool
+	MakeBundleSourcePath(source string, version string) string
+	LoadManifests(path string) (*bundlefile.Schema, *lockfile.Schema, error)
+}
+
+// BuildFromStorage builds the graph of the bundle located in the
+// directory, lock files of its requirements are loaded from the storage
+func BuildFromStorage(s graphStorage, dir string) (*Graph, error) {
+	bundleFile, lockFile, err := s.LoadManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if bundleFile.Package == nil {
+		return nil, fmt.Errorf("package block is not defined")
+	}
+
+	return Build(&BuildInput{
+		Name:       bundleFile.Package.Name,
+		Repository: bundleFile.Package.Repository,
+		LockFile:   lockFile,
+		Load: func(source, version string) (*lockfile.Schema, error) {
+			if !s.Some(source, version) {
+				return nil, fmt.Errorf("bundle %s is not installed\n\t> run 'bpm install' to install it",
+					bundleutil.FormatSourceWithVersion(source, version),
+				)
+			}
+
+			_, lf, err := s.LoadManifests(s.MakeBundleSourcePath(source, version))
+			return lf, err
+		},
+	})
+}
+
+type builder struct {
+	graph    *Graph
+	bySource map[string]*BundleNode
+	modules  map[string]*ModuleNode
+	edges    map[Edge]struct{}
+}
+
+// Build builds the graph of the root bundle. All requirements are taken from
+// the root lock file, since it holds the selected version of every bundle,
+// while the requirements of each bundle and the imports of its modules are
+// taken from its own lock file.
+func Build(input *BuildInput) (*Graph, error) {
+	b := &builder{
+		graph: &Graph{
+			Root:        input.Repository,
+			Bundles:     make([]*BundleNode, 0),
+			BundleEdges: make([]*Edge, 0),
+			Modules:     make([]*ModuleNode, 0),
+			ModuleEdges: make([]*Edge, 0),
+		},
+		bySource: make(map[string]*BundleNode),
+		modules:  make(map[string]*ModuleNode),
+		edges:    make(map[Edge]struct{}),
+	}
+
+	root := &BundleNode{ID: input.Repository, Source: input.Repository, Name: input.Name}
+	b.addBundle(root)
+
+	lockFile := lockfile.PrepareSchema(input.LockFile)
+	for _, r := range lockFile.Require.List {
+		b.addBundle(&BundleNode{
+			ID:        bundleutil.FormatSourceWithVersion(r.Source, r.Version),
+			Source:    r.Source,
+			Name:      r.Name,
+			Version:   r.Version,
+			Direction: r.Direction,
+		})
+	}
+
+	if err := b.addLockFile(root, lockFile); err != nil {
+		return nil, err
+	}
+
+	for _, node := range b.graph.Bundles[1:] {
+		lf, err := input.Load(node.Source, node.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load lock file of %s: %v", node.ID, err)
+		}
+
+		if err := b.addLockFile(node, lockfile.PrepareSchema(lf)); err != nil {
+			return nil, err
+		}
+	}
+
+	b.graph.sort()
+	return b.graph, nil
+}
+
+func (b *builder) addBundle(node *BundleNode) {
+	b.graph.Bundles = append(b.graph.Bundles, node)
+	b.bySource[node.Source] = node
+}
+
+func (b *builder) addModule(node *ModuleNode) *ModuleNode {
+	if existing, exists := b.modules[node.Package]; exists {
+		return existing
+	}
+
+	b.modules[node.Package] = node
+	b.graph.Modules = append(b.graph.Modules, node)
+	return node
+}
+
+func (b *builder) addEdge(list *[]*Edge, edge Edge) {
+	if _, exists := b.edges[edge]; exists {
+		return
+	}
+
+	b.edges[edge] = struct{}{}
+	*list = append(*list, &edge)
+}
+
+// addLockFile adds the requirements and the modules of the bundle node
+func (b *builder) addLockFile(node *BundleNode, lf *lockfile.Schema) error {
+	for _, r := range lf.Require.List {
+		if r.Direction != lockfile.Direct.String() {
+			continue
+		}
+
+		// the version selected for the root bundle is used, which can
+		// be higher than the one the bundle has been locked with
+		if target, exists := b.bySource[r.Source]; exists {
+			b.addEdge(&b.graph.BundleEdges, Edge{From: node.ID, To: target.ID})
+		}
+	}
+
+	for _, m := range lf.Consist.List {
+		module := b.addModule(&ModuleNode{Package: m.Package})
+		module.Bundle = node.ID
+		module.Visibility = m.Visibility
+		module.File = m.Source
+
+		for _, specStr := range m.Require {
+			spec, err := lockfile.ParseModRequireSpec(specStr)
+			if err != nil {
+				return fmt.Errorf("invalid lock file of %s: %v", node.ID, err)
+			}
+
+			target := &ModuleNode{Package: spec.Module}
+			if source, _, ok := cutVersion(spec.Source); ok {
+				if owner, exists := b.bySource[source]; exists {
+					target.Bundle = owner.ID
+				}
+			}
+
+			b.addModule(target)
+			b.addEdge(&b.graph.ModuleEdges, Edge{From: m.Package, To: spec.Module, File: m.Source, Line: spec.Line})
+		}
+	}
+
+	return nil
+}
+
+// Focus returns the subgraph of the modules of the package, the modules they
+// import or are imported by, and the bundles that these modules belong to
+func (g *Graph) Focus(pkg string) (*Graph, error) {
+	pkg = strings.TrimPrefix(pkg, regofile.ImportPathPrefix)
+	matches := func(p string) bool {
+		return p == pkg || strings.HasPrefix(p, pkg+".")
+	}
+
+	keepModules := make(map[string]struct{})
+	for _, m := range g.Modules {
+		if matches(m.Package) {
+			keepModules[m.Package] = struct{}{}
+		}
+	}
+
+	if len(keepModules) == 0 {
+		return nil, fmt.Errorf("module '%s' is not found", pkg)
+	}
+
+	result := &Graph{
+		Root:        g.Root,
+		Bundles:     make([]*BundleNode, 0),
+		BundleEdges: make([]*Edge, 0),
+		Modules:     make([]*ModuleNode, 0),
+		ModuleEdges: make([]*Edge, 0),
+	}
+
+	for _, e := range g.ModuleEdges {
+		if matches(e.From) || matches(e.To) {
+			result.ModuleEdges = append(result.ModuleEdges, e)
+			keepModules[e.From] = struct{}{}
+			keepModules[e.To] = struct{}{}
+		}
+	}
+
+	keepBundles := make(map[string]struct{})
+	for _, m := range g.Modules {
+		if _, keep := keepModules[m.Package]; keep {
+			result.Modules = append(result.Modules, m)
+			keepBundles[m.Bundle] = struct{}{}
+		}
+	}
+
+	for _, n := range g.Bundles {
+		if _, keep := keepBundles[n.ID]; keep {
+			result.Bundles = append(result.Bundles, n)
+		}
+	}
+
+	for _, e := range g.BundleEdges {
+		_, keepFrom := keepBundles[e.From]
+		_, keepTo := keepBundles[e.To]
+		if keepFrom && keepTo {
+			result.BundleEdges = append(result.BundleEdges, e)
+		}
+	}
+
+	return result, nil
+}
+
+// sort orders modules and edges, bundles keep the order of
+// the lock file, so that the root and direct requirements go first
+func (g *Graph) sort() {
+	sort.Slice(g.Modules, func(i, j int) bool {
+		return g.Modules[i].Package < g.Modules[j].Package
+	})
+
+	sortEdges(g.BundleEdges)
+	sortEdges(g.ModuleEdges)
+}
+
+func sortEdges(edges []*Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		switch {
+		case a.From != b.From:
+			return a.From < b.From
+		case a.To != b.To:
+			return a.To < b.To
+		case a.File != b.File:
+			return a.File < b.File
+		default:
+			return a.Line < b.Line
+		}
+	})
+}
+
+// cutVersion splits 'source@version' into the source and the version
+func cutVersion(str string) (source, version string, ok bool) {
+	idx := strings.LastIndex(str, "@")
+	if idx == -1 {
+		return str, "", false
+	}
+
+	return str[:idx], str[idx+1:], true
+}
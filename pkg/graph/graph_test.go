@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/stretchr/testify/require"
+)
+
+func newLockFile(requires []*lockfile.RequirementDecl, modules ...*lockfile.ModuleDecl) *lockfile.Schema {
+	return &lockfile.Schema{
+		Consist: &lockfile.ConsistBlock{List: modules},
+		Require: &lockfile.RequireBlock{List: requires},
+	}
+}
+
+func newRequirement(source, name, version string, direction lockfile.DirectionType) *lockfile.RequirementDecl {
+	return &lockfile.RequirementDecl{Source: source, Name: name, Version: version, Direction: direction.String()}
+}
+
+// testGraph builds the graph of 'root -> a -> b', where
+// root and a import modules of a and b respectively
+func testGraph(t *testing.T) *Graph {
+	deps := map[string]*lockfile.Schema{
+		"github.com/org/a@v1.2.0": newLockFile(
+			[]*lockfile.RequirementDecl{newRequirement("github.com/org/b", "b", "v0.1.0", lockfile.Direct)},
+			&lockfile.ModuleDecl{Package: "a.rules", Source: "rules.rego", Require: []string{"4:github.com/org/b@v0.1.0:b.lib"}},
+		),
+		"github.com/org/b@v0.2.0": newLockFile(nil,
+			&lockfile.ModuleDecl{Package: "b.lib", Source: "lib.rego"},
+		),
+	}
+
+	g, err := Build(&BuildInput{
+		Name:       "root",
+		Repository: "github.com/org/root",
+		LockFile: newLockFile(
+			[]*lockfile.RequirementDecl{
+				newRequirement("github.com/org/a", "a", "v1.2.0", lockfile.Direct),
+				newRequirement("github.com/org/b", "b", "v0.2.0", lockfile.Indirect),
+			},
+			&lockfile.ModuleDecl{Package: "root.main", Source: "main.rego", Require: []string{"3:github.com/org/a@v1.2.0:a.rules"}},
+			&lockfile.ModuleDecl{Package: "root.util", Source: "util.rego"},
+		),
+		Load: func(source, version string) (*lockfile.Schema, error) {
+			lf, exists := deps[source+"@"+version]
+			if !exists {
+				return nil, fmt.Errorf("not found")
+			}
+
+			return lf, nil
+		},
+	})
+	require.NoError(t, err)
+
+	return g
+}
+
+func TestBuild(t *testing.T) {
+	g := testGraph(t)
+
+	require.Equal(t, "github.com/org/root", g.Root)
+	require.Len(t, g.Bundles, 3)
+	require.Equal(t, []*Edge{
+		{From: "github.com/org/a@v1.2.0", To: "github.com/org/b@v0.2.0"},
+		{From: "github.com/org/root", To: "github.com/org/a@v1.2.0"},
+	}, g.BundleEdges, "requirements must point to the selected versions")
+
+	require.Equal(t, []*Edge{
+		{From: "a.rules", To: "b.lib", File: "rules.rego", Line: 4},
+		{From: "root.main", To: "a.rules", File: "main.rego", Line: 3},
+	}, g.ModuleEdges)
+
+	packages := make([]string, len(g.Modules))
+	for i, m := range g.Modules {
+		packages[i] = m.Package
+	}
+	require.Equal(t, []string{"a.rules", "b.lib", "root.main", "root.util"}, packages)
+}
+
+func TestFocus(t *testing.T) {
+	g, err := testGraph(t).Focus("data.a")
+	require.NoError(t, err)
+
+	require.Len(t, g.Modules, 3)
+	require.Len(t, g.ModuleEdges, 2)
+	require.Len(t, g.Bundles, 3)
+
+	g, err = testGraph(t).Focus("b.lib")
+	require.NoError(t, err)
+	require.Equal(t, []*Edge{{From: "github.com/org/a@v1.2.0", To: "github.com/org/b@v0.2.0"}}, g.BundleEdges)
+
+	_, err = testGraph(t).Focus("unknown")
+	require.Error(t, err)
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testGraph(t), FormatDOT))
+	require.Contains(t, buf.String(), `"module:root.main" -> "module:a.rules" [label="main.rego:3"];`)
+	require.Contains(t, buf.String(), `"bundle:github.com/org/b@v0.2.0" [label="b\ngithub.com/org/b@v0.2.0", style=dashed];`)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, testGraph(t), FormatMermaid))
+	require.Contains(t, buf.String(), `m2 -->|"main.rego:3"| m0`)
+
+	require.Error(t, Write(&buf, testGraph(t), Format("svg")))
+}
+
+func TestShortestChains(t *testing.T) {
+	g := &Graph{
+		Root: "root",
+		Bundles: []*BundleNode{
+			{ID: "root", Source: "root"},
+			{ID: "a@v1", Source: "a"},
+			{ID: "b@v1", Source: "b"},
+			{ID: "c@v1", Source: "c"},
+			{ID: "d@v1", Source: "d"},
+		},
+		BundleEdges: []*Edge{
+			{From: "root", To: "a@v1"},
+			{From: "root", To: "b@v1"},
+			{From: "a@v1", To: "c@v1"},
+			{From: "b@v1", To: "c@v1"},
+			{From: "c@v1", To: "d@v1"},
+			{From: "root", To: "d@v1"},
+		},
+	}
+
+	chains, err := g.ShortestChains("c")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"root", "a@v1", "c@v1"}, {"root", "b@v1", "c@v1"}}, chains)
+
+	chains, err = g.ShortestChains("d")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"root", "d@v1"}}, chains)
+
+	_, err = g.ShortestChains("e")
+	require.Error(t, err)
+}
+
+func TestImportsOf(t *testing.T) {
+	lf := newLockFile(nil,
+		&lockfile.ModuleDecl{Package: "root.main", Source: "main.rego", Require: []string{
+			"3:github.com/org/a@v1.2.0:a.rules",
+			"4:github.com/org/a@v1.2.0:a.rulesets",
+		}},
+		&lockfile.ModuleDecl{Package: "root.util", Source: "util.rego", Require: []string{"7:https://example.com/a@v1.2.0:a.rules.deny"}},
+	)
+
+	edges, err := ImportsOf(lf, "data.a.rules")
+	require.NoError(t, err)
+	require.Equal(t, []*Edge{
+		{From: "root.main", To: "a.rules", File: "main.rego", Line: 3},
+		{From: "root.util", To: "a.rules.deny", File: "util.rego", Line: 7},
+	}, edges)
+}
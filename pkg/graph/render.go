@@ -0,0 +1,202 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+)
+
+// Format is the output format of the graph
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+	FormatJSON    Format = "json"
+)
+
+var Formats = [...]Format{FormatDOT, FormatMermaid, FormatJSON}
+
+// Write renders the graph in the given format
+func Write(w io.Writer, g *Graph, format Format) error {
+	switch format {
+	case FormatDOT:
+		return WriteDOT(w, g)
+	case FormatMermaid:
+		return WriteMermaid(w, g)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(g)
+	default:
+		return fmt.Errorf("unknown graph format '%s'", format)
+	}
+}
+
+// WriteDOT renders the graph in the Graphviz DOT language. Bundles and
+// modules are placed into separate clusters, modules are additionally
+// grouped by the bundle they belong to.
+func WriteDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph bpm {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=box];")
+
+	fmt.Fprintln(bw, "  subgraph cluster_bundles {")
+	fmt.Fprintln(bw, `    label="bundles";`)
+	for _, n := range g.Bundles {
+		style := ""
+		if n.Direction == lockfile.Indirect.String() {
+			style = ", style=dashed"
+		}
+
+		fmt.Fprintf(bw, "    %s [label=%s%s];\n", dotID("bundle", n.ID), dotQuote(bundleLabel(n)), style)
+	}
+	for _, e := range g.BundleEdges {
+		fmt.Fprintf(bw, "    %s -> %s;\n", dotID("bundle", e.From), dotID("bundle", e.To))
+	}
+	fmt.Fprintln(bw, "  }")
+
+	fmt.Fprintln(bw, "  subgraph cluster_modules {")
+	fmt.Fprintln(bw, `    label="modules";`)
+	for i, group := range groupModules(g) {
+		fmt.Fprintf(bw, "    subgraph cluster_modules_%d {\n", i)
+		fmt.Fprintf(bw, "      label=%s;\n", dotQuote(group.label))
+		for _, m := range group.modules {
+			fmt.Fprintf(bw, "      %s [label=%s];\n", dotID("module", m.Package), dotQuote(m.Package))
+		}
+		fmt.Fprintln(bw, "    }")
+	}
+	for _, e := range g.ModuleEdges {
+		fmt.Fprintf(bw, "    %s -> %s [label=%s];\n",
+			dotID("module", e.From), dotID("module", e.To), dotQuote(edgeLabel(e)),
+		)
+	}
+	fmt.Fprintln(bw, "  }")
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteMermaid renders the graph as a Mermaid flowchart, the
+// layout is the same as the one of the DOT representation
+func WriteMermaid(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	// mermaid identifiers cannot contain most of the characters
+	// used in sources and packages, so that nodes are numbered
+	ids := make(map[string]string, len(g.Bundles)+len(g.Modules))
+	for i, n := range g.Bundles {
+		ids["bundle:"+n.ID] = fmt.Sprintf("b%d", i)
+	}
+	for i, m := range g.Modules {
+		ids["module:"+m.Package] = fmt.Sprintf("m%d", i)
+	}
+
+	fmt.Fprintln(bw, "flowchart LR")
+
+	fmt.Fprintln(bw, "  subgraph bundles")
+	for _, n := range g.Bundles {
+		fmt.Fprintf(bw, "    %s[%s]\n", ids["bundle:"+n.ID], mermaidQuote(bundleLabel(n)))
+	}
+	for _, e := range g.BundleEdges {
+		arrow := "-->"
+		if to := g.bundle(e.To); to != nil && to.Direction == lockfile.Indirect.String() {
+			arrow = "-.->"
+		}
+
+		fmt.Fprintf(bw, "    %s %s %s\n", ids["bundle:"+e.From], arrow, ids["bundle:"+e.To])
+	}
+	fmt.Fprintln(bw, "  end")
+
+	fmt.Fprintln(bw, "  subgraph modules")
+	for i, group := range groupModules(g) {
+		fmt.Fprintf(bw, "    subgraph modules_%d [%s]\n", i, mermaidQuote(group.label))
+		for _, m := range group.modules {
+			fmt.Fprintf(bw, "      %s[%s]\n", ids["module:"+m.Package], mermaidQuote(m.Package))
+		}
+		fmt.Fprintln(bw, "    end")
+	}
+	for _, e := range g.ModuleEdges {
+		fmt.Fprintf(bw, "    %s -->|%s| %s\n", ids["module:"+e.From], mermaidQuote(edgeLabel(e)), ids["module:"+e.To])
+	}
+	fmt.Fprintln(bw, "  end")
+
+	return bw.Flush()
+}
+
+type moduleGroup struct {
+	label   string
+	modules []*ModuleNode
+}
+
+// groupModules groups the modules by their bundles in the order of bundles,
+// modules of the unknown bundles are placed into the last group
+func groupModules(g *Graph) []*moduleGroup {
+	byBundle := make(map[string][]*ModuleNode)
+	for _, m := range g.Modules {
+		byBundle[m.Bundle] = append(byBundle[m.Bundle], m)
+	}
+
+	result := make([]*moduleGroup, 0, len(byBundle))
+	for _, n := range g.Bundles {
+		if modules, exists := byBundle[n.ID]; exists {
+			result = append(result, &moduleGroup{label: n.ID, modules: modules})
+			delete(byBundle, n.ID)
+		}
+	}
+
+	unknown := make([]*ModuleNode, 0)
+	for _, m := range g.Modules {
+		if _, exists := byBundle[m.Bundle]; exists {
+			unknown = append(unknown, m)
+		}
+	}
+
+	if len(unknown) > 0 {
+		result = append(result, &moduleGroup{label: "unknown", modules: unknown})
+	}
+
+	return result
+}
+
+func (g *Graph) bundle(id string) *BundleNode {
+	for _, n := range g.Bundles {
+		if n.ID == id {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func bundleLabel(n *BundleNode) string {
+	if n.Version == "" {
+		return n.Name
+	}
+
+	return n.Name + "\n" + n.ID
+}
+
+func edgeLabel(e *Edge) string {
+	return fmt.Sprintf("%s:%d", e.File, e.Line)
+}
+
+func dotID(kind, id string) string {
+	return dotQuote(kind + ":" + id)
+}
+
+func dotQuote(str string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(str) + `"`
+}
+
+func mermaidQuote(str string) string {
+	replacer := strings.NewReplacer(`"`, "#quot;", "\n", "<br>")
+	return `"` + replacer.Replace(str) + `"`
+}
@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundle/regofile"
+)
+
+// ShortestChains returns all shortest requirement chains from the root
+// bundle to the bundle with the given source, each chain is a list of
+// bundle ids that starts with the root bundle
+func (g *Graph) ShortestChains(source string) ([][]string, error) {
+	var target *BundleNode
+	for _, n := range g.Bundles {
+		if n.Source == source || n.ID == source {
+			target = n
+			break
+		}
+	}
+
+	switch {
+	case target == nil:
+		return nil, fmt.Errorf("bundle %s is not required by %s", source, g.Root)
+	case target.ID == g.Root:
+		return nil, fmt.Errorf("bundle %s is the root bundle", source)
+	}
+
+	adjacency := make(map[string][]string)
+	for _, e := range g.BundleEdges {
+		adjacency[e.From] = append(adjacency[e.From], e.To)
+	}
+
+	// breadth-first search that remembers every parent on a
+	// shortest path, so that all shortest chains can be restored
+	depth := map[string]int{g.Root: 0}
+	parents := make(map[string][]string)
+	for level := []string{g.Root}; len(level) > 0; {
+		next := make([]string, 0)
+		for _, id := range level {
+			for _, to := range adjacency[id] {
+				d, seen := depth[to]
+				if !seen {
+					depth[to] = depth[id] + 1
+					next = append(next, to)
+				} else if d != depth[id]+1 {
+					continue
+				}
+
+				parents[to] = append(parents[to], id)
+			}
+		}
+
+		level = next
+	}
+
+	if _, reached := depth[target.ID]; !reached {
+		return nil, fmt.Errorf("bundle %s is not reachable from %s", target.ID, g.Root)
+	}
+
+	result := restoreChains(target.ID, g.Root, parents)
+	sort.Slice(result, func(i, j int) bool {
+		return strings.Join(result[i], " ") < strings.Join(result[j], " ")
+	})
+
+	return result, nil
+}
+
+func restoreChains(id, root string, parents map[string][]string) [][]string {
+	if id == root {
+		return [][]string{{root}}
+	}
+
+	result := make([][]string, 0)
+	for _, parent := range parents[id] {
+		for _, chain := range restoreChains(parent, root, parents) {
+			result = append(result, append(chain, id))
+		}
+	}
+
+	return result
+}
+
+// ImportsOf returns the imports of the module recorded in the lock file,
+// edges lead from the importing modules and point to the import declarations
+func ImportsOf(lf *lockfile.Schema, module string) ([]*Edge, error) {
+	module = strings.TrimPrefix(module, regofile.ImportPathPrefix)
+	result := make([]*Edge, 0)
+
+	for _, m := range lockfile.PrepareSchema(lf).Consist.List {
+		for _, specStr := range m.Require {
+			spec, err := lockfile.ParseModRequireSpec(specStr)
+			if err != nil {
+				return nil, err
+			}
+
+			if spec.Module != module && !strings.HasPrefix(spec.Module, module+".") {
+				continue
+			}
+
+			result = append(result, &Edge{From: m.Package, To: spec.Module, File: m.Source, Line: spec.Line})
+		}
+	}
+
+	sortEdges(result)
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package linker
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	opabundle "github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/format"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// dataFileNames are the names of the files that OPA
+// loads as documents of the directory they are located in
+var dataFileNames = map[string]struct{}{
+	"data.json": {},
+	"data.yaml": {},
+	"data.yml":  {},
+}
+
+// Build assembles linked bundles into a single OPA bundle. Every bundle
+// becomes one of the manifest roots, named after the bundle, and its modules
+// and data files are placed under the directory with the same name.
+func Build(linked []*LinkedBundle) (*opabundle.Bundle, error) {
+	if len(linked) == 0 {
+		return nil, fmt.Errorf("nothing to build")
+	}
+
+	roots := make([]string, 0, len(linked))
+	owners := make(map[string]string, len(linked))
+	result := &opabundle.Bundle{
+		Data:    make(map[string]interface{}),
+		Modules: make([]opabundle.ModuleFile, 0),
+	}
+
+	for _, lb := range linked {
+		name := lb.Bundle.Name()
+		if owner, exists := owners[name]; exists {
+			return nil, fmt.Errorf("bundle name '%s' is used by both %s and %s", name, owner, lb.Bundle.Repository())
+		}
+
+		owners[name] = lb.Bundle.Repository()
+		roots = append(roots, name)
+
+		for _, filePath := range sortedKeys(lb.Modules) {
+			raw, err := format.Ast(lb.Modules[filePath])
+			if err != nil {
+				return nil, fmt.Errorf("failed to format module %s of %s: %v", filePath, lb.Bundle.Repository(), err)
+			}
+
+			modulePath := path.Join("/", name, filePath)
+			result.Modules = append(result.Modules, opabundle.ModuleFile{
+				URL:    modulePath,
+				Path:   modulePath,
+				Raw:    raw,
+				Parsed: lb.Modules[filePath],
+			})
+		}
+
+		for _, filePath := range sortedKeys(lb.Bundle.OtherFiles) {
+			if _, isData := dataFileNames[path.Base(filePath)]; !isData {
+				continue
+			}
+
+			var value interface{}
+			if err := util.Unmarshal(lb.Bundle.OtherFiles[filePath], &value); err != nil {
+				return nil, fmt.Errorf("failed to parse data file %s of %s: %v", filePath, lb.Bundle.Repository(), err)
+			}
+
+			dataPath := append([]string{name}, splitDir(filePath)...)
+			if err := insertData(result.Data, dataPath, value); err != nil {
+				return nil, fmt.Errorf("failed to load data file %s of %s: %v", filePath, lb.Bundle.Repository(), err)
+			}
+		}
+	}
+
+	result.Manifest = opabundle.Manifest{
+		Revision: linked[0].Bundle.LockFile.Sum,
+		Roots:    &roots,
+	}
+
+	return result, nil
+}
+
+// Compile compiles all modules of the OPA bundle, so that
+// errors are reported before the bundle is used anywhere
+func Compile(b *opabundle.Bundle) (*ast.Compiler, error) {
+	modules := make(map[string]*ast.Module, len(b.Modules))
+	for _, mf := range b.Modules {
+		modules[mf.Path] = mf.Parsed
+	}
+
+	compiler := ast.NewCompiler().WithEnablePrintStatements(true)
+	if compiler.Compile(modules); compiler.Failed() {
+		return nil, compiler.Errors
+	}
+
+	return compiler, nil
+}
+
+// splitDir returns the directory segments of the slash-separated file path
+func splitDir(filePath string) []string {
+	dir := path.Dir(filePath)
+	if dir == "." {
+		return nil
+	}
+
+	return strings.Split(dir, "/")
+}
+
+// insertData merges the value into the document at the given path,
+// objects are merged recursively, any other values must not overlap
+func insertData(doc map[string]interface{}, dataPath []string, value interface{}) error {
+	for i, key := range dataPath[:len(dataPath)-1] {
+		next, exists := doc[key]
+		if !exists {
+			next = make(map[string]interface{})
+			doc[key] = next
+		}
+
+		obj, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("document data.%s is not an object", strings.Join(dataPath[:i+1], "."))
+		}
+
+		doc = obj
+	}
+
+	return mergeData(doc, dataPath, value)
+}
+
+func mergeData(doc map[string]interface{}, dataPath []string, value interface{}) error {
+	key := dataPath[len(dataPath)-1]
+	existing, exists := doc[key]
+	if !exists {
+		doc[key] = value
+		return nil
+	}
+
+	existingObj, ok1 := existing.(map[string]interface{})
+	valueObj, ok2 := value.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return fmt.Errorf("document data.%s is defined more than once", strings.Join(dataPath, "."))
+	}
+
+	for k, v := range valueObj {
+		if err := mergeData(existingObj, append(dataPath[:len(dataPath):len(dataPath)], k), v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
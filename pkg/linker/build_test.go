@@ -0,0 +1,90 @@
+package linker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/iostream"
+	opabundle "github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/stretchr/testify/require"
+)
+
+func newLinkedBundle(t *testing.T, files map[string][]byte) *LinkedBundle {
+	encoder := &encode.Encoder{IO: iostream.NewIOStream(iostream.WithOutput(io.Discard))}
+
+	b, err := archive.DecodeBundle(files, encoder)
+	require.NoError(t, err)
+	b.LockFile.Sum = b.Sum()
+
+	return linkBundle(b)
+}
+
+func testLinkedBundles(t *testing.T) []*LinkedBundle {
+	head := newLinkedBundle(t, map[string][]byte{
+		constant.BundleFileName: []byte(`package {
+  name       = "head"
+  repository = "example.com/head"
+}
+
+require {
+  bundle "example.com/dep" {
+    name    = "dep"
+    version = "v1.0.0"
+  }
+}
+`),
+		"rules/main.rego":  []byte("package head.rules.main\n\nimport data.dep.lib\n\nallow := lib.limit > data.head.config.min\n"),
+		"config/data.json": []byte(`{"min": 1}`),
+		"README.md":        []byte("# head"),
+	})
+
+	dep := newLinkedBundle(t, map[string][]byte{
+		constant.BundleFileName: []byte(`package {
+  name       = "dep"
+  repository = "example.com/dep"
+}
+`),
+		"lib.rego":  []byte("package dep.lib\n\nlimit := data.dep.limit\n"),
+		"data.yaml": []byte("limit: 10\n"),
+	})
+
+	return []*LinkedBundle{head, dep}
+}
+
+func TestBuild(t *testing.T) {
+	result, err := Build(testLinkedBundles(t))
+	require.NoError(t, err)
+	require.Equal(t, []string{"head", "dep"}, *result.Manifest.Roots)
+
+	_, err = Compile(result)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, opabundle.NewWriter(&buf).UseModulePath(true).Write(*result))
+
+	loaded, err := opabundle.NewReader(&buf).Read()
+	require.NoError(t, err)
+	require.Equal(t, []string{"head", "dep"}, *loaded.Manifest.Roots)
+	require.Len(t, loaded.Modules, 2)
+
+	rs, err := rego.New(
+		rego.Query("data.head.rules.main.allow"),
+		rego.ParsedBundle("test", &loaded),
+	).Eval(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, true, rs[0].Expressions[0].Value)
+}
+
+func TestBuildDuplicateName(t *testing.T) {
+	linked := testLinkedBundles(t)
+	linked[1].Bundle.BundleFile.Package.Name = "head"
+
+	_, err := Build(linked)
+	require.ErrorContains(t, err, "bundle name 'head' is used by both")
+}
@@ -25,31 +25,44 @@ type Linker struct {
 	Inspector  linkerInspector
 }
 
+// LinkedBundle is a bundle with its modules prepared to be
+// evaluated together with the modules of its requirements
+type LinkedBundle struct {
+	Bundle  *bundle.Bundle
+	Modules map[string]*ast.Module // modules by the file path inside of the bundle
+}
+
 func (l *Linker) Link(ctx context.Context, b *bundle.Bundle) (map[string]*ast.Module, error) {
-	if err := l.Manifester.SyncLockfile(ctx, b); err != nil {
+	linked, err := l.LinkBundles(ctx, b)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := l.Inspector.Inspect(b); err != nil {
-		return nil, err
+	result := make(map[string]*ast.Module, len(b.RegoFiles))
+	for _, lb := range linked {
+		for filePath, m := range lb.Modules {
+			result[filePath] = m
+		}
 	}
 
-	result := make(map[string]*ast.Module, len(b.RegoFiles))
-	requireCache := make(map[string]struct{}, len(b.BundleFile.Require.List))
+	return result, nil
+}
 
-	for _, r := range b.BundleFile.Require.List {
-		requireCache[r.Name] = struct{}{}
+// LinkBundles links the bundle the same way as `Link` does, but keeps the
+// modules of each bundle separately. The head bundle always goes first,
+// followed by all of its requirements in the order of the lock file.
+func (l *Linker) LinkBundles(ctx context.Context, b *bundle.Bundle) ([]*LinkedBundle, error) {
+	if err := l.Manifester.SyncLockfile(ctx, b); err != nil {
+		return nil, err
 	}
 
-	for filePath, f := range b.RegoFiles {
-		// saving this file as a module of the head bundle
-		result[filePath] = ProcessModule(
-			f.Parsed,
-			WithImportProcessing(getRequireList(b)),
-		)
-
+	if err := l.Inspector.Inspect(b); err != nil {
+		return nil, err
 	}
 
+	result := make([]*LinkedBundle, 0, len(b.LockFile.Require.List)+1)
+	result = append(result, linkBundle(b))
+
 	// iter over all required bundles
 	for _, item := range b.LockFile.Require.List {
 		v, err := bundle.ParseVersionExpr(item.Version)
@@ -62,14 +75,23 @@ func (l *Linker) Link(ctx context.Context, b *bundle.Bundle) (map[string]*ast.Mo
 			return nil, err
 		}
 
-		// save all the modules of the required bundle
-		for filePath, f := range itemBundle.RegoFiles {
-			result[filePath] = ProcessModule(
-				f.Parsed,
-				WithImportProcessing(getRequireList(itemBundle)),
-			)
-		}
+		result = append(result, linkBundle(itemBundle))
 	}
 
 	return result, nil
 }
+
+func linkBundle(b *bundle.Bundle) *LinkedBundle {
+	modules := make(map[string]*ast.Module, len(b.RegoFiles))
+	for filePath, f := range b.RegoFiles {
+		modules[filePath] = ProcessModule(
+			f.Parsed,
+			WithImportProcessing(getRequireList(b)),
+		)
+	}
+
+	return &LinkedBundle{
+		Bundle:  b,
+		Modules: modules,
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/4rchr4y/bpm/bundleutil/inspect"
 	"github.com/4rchr4y/bpm/bundleutil/manifest"
 	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/fetch/remote"
 	"github.com/4rchr4y/bpm/iostream"
+	"github.com/4rchr4y/bpm/pkg/resolver"
 	"github.com/open-policy-agent/opa/ast"
 
 	"github.com/4rchr4y/bpm/storage"
@@ -48,7 +50,9 @@ func TestLink(t *testing.T) {
 			IO:      io,
 			Client:  nil,
 			Encoder: encoder,
+			Sources: &remote.Resolver{},
 		},
+		Sources: &remote.Resolver{},
 	}
 
 	manifester := &manifest.Manifester{
@@ -57,6 +61,10 @@ func TestLink(t *testing.T) {
 		Storage: s,
 		Encoder: encoder,
 		Fetcher: fetcher,
+		Resolver: &resolver.Resolver{
+			IO:      io,
+			Fetcher: fetcher,
+		},
 	}
 
 	b, err := s.LoadFromAbs("../../testdata/testbundle", nil)
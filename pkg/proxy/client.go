@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil"
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/fetch/remote"
+	"github.com/hashicorp/go-version"
+)
+
+type clientEncoder interface {
+	DecodeBundleFile(content []byte) (*bundlefile.Schema, error)
+	DecodeIgnoreFile(content []byte) (*bundle.IgnoreFile, error)
+	DecodeLockFile(content []byte) (*lockfile.Schema, error)
+	Fileify(files map[string][]byte) (*encode.FileifyOutput, error)
+}
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client downloads bundles from a bundle proxy
+type Client struct {
+	IO      core.IO
+	URL     string // base URL of the proxy
+	HTTP    httpClient
+	Encoder clientEncoder
+}
+
+func (c *Client) Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error) {
+	if strings.Contains(source, ":") {
+		// explicit URLs and scp-like addresses can only be cloned directly
+		return nil, fmt.Errorf("source %s is not supported by proxy: %w", remote.Redact(source), fetch.ErrNotFound)
+	}
+
+	version, err := c.resolveVersion(ctx, source, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	c.IO.PrintfInfo("downloading %s from %s", bundleutil.FormatSourceWithVersion(source, version), remote.Redact(c.URL))
+
+	info, err := c.Info(ctx, source, version)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := bundle.ParseVersionExpr(info.Version)
+	if err != nil || v == nil || v.IsConstraint() {
+		return nil, fmt.Errorf("proxy returned invalid version '%s' of %s", info.Version, source)
+	}
+
+	if !v.IsPseudo() {
+		v.Timestamp = info.Time.UTC()
+		v.Hash = info.Hash
+	}
+
+	content, err := c.Zip(ctx, source, version)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := archive.ReadZip(content)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := archive.DecodeBundle(files, c.Encoder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive of %s: %v", bundleutil.FormatSourceWithVersion(source, version), err)
+	}
+
+	b.Source = source
+	b.Version = v
+
+	return b, nil
+}
+
+// resolveVersion turns the requested version into a concrete version
+// string, the latest version and constraints are resolved using the
+// list of versions known by the proxy
+func (c *Client) resolveVersion(ctx context.Context, source string, tag *bundle.VersionSpec) (string, error) {
+	if tag != nil && !tag.IsConstraint() {
+		return tag.String(), nil
+	}
+
+	list, err := c.List(ctx, source)
+	if err != nil {
+		return "", err
+	}
+
+	var latest *version.Version
+	for _, item := range list {
+		v, err := version.NewSemver(item)
+		if err != nil || v.Original() == bundle.PseudoSemTagStr {
+			continue
+		}
+
+		if tag.IsConstraint() && !tag.Constraint.Check(v) {
+			continue
+		}
+
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+
+	if latest == nil {
+		return "", fmt.Errorf("no version of %s matching '%s' is known by proxy: %w", source, tag.String(), fetch.ErrNotFound)
+	}
+
+	return latest.Original(), nil
+}
+
+// List returns all versions of the source known by the proxy
+func (c *Client) List(ctx context.Context, source string) ([]string, error) {
+	content, err := c.get(ctx, ListPath(source))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			result = append(result, line)
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// ListVersions returns the semantic versions of the source known
+// by the proxy in ascending order, pseudo versions are skipped
+func (c *Client) ListVersions(ctx context.Context, source string) ([]*version.Version, error) {
+	if strings.Contains(source, ":") {
+		return nil, fmt.Errorf("source %s is not supported by proxy: %w", remote.Redact(source), fetch.ErrNotFound)
+	}
+
+	list, err := c.List(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*version.Version, 0, len(list))
+	for _, item := range list {
+		v, err := bundle.ParseVersionExpr(item)
+		if err != nil || v == nil || v.IsConstraint() || v.IsPseudo() {
+			continue
+		}
+
+		result = append(result, v.SemTag)
+	}
+
+	sort.Sort(version.Collection(result))
+	return result, nil
+}
+
+func (c *Client) Info(ctx context.Context, source, version string) (*VersionInfo, error) {
+	content, err := c.get(ctx, InfoPath(source, version))
+	if err != nil {
+		return nil, err
+	}
+
+	info := new(VersionInfo)
+	if err := json.Unmarshal(content, info); err != nil {
+		return nil, fmt.Errorf("failed to decode version info of %s: %v", bundleutil.FormatSourceWithVersion(source, version), err)
+	}
+
+	return info, nil
+}
+
+func (c *Client) Zip(ctx context.Context, source, version string) ([]byte, error) {
+	return c.get(ctx, ZipPath(source, version))
+}
+
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.URL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		// the error contains the request URL, which could contain credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return nil, fmt.Errorf("failed to reach proxy %s: %v", remote.Redact(c.URL), err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%s: %w", path, fetch.ErrNotFound)
+	default:
+		return nil, fmt.Errorf("%s: unexpected status %s", path, resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, archive.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) > archive.MaxSize {
+		return nil, fmt.Errorf("%s: response exceeds maximum size of %d bytes", path, archive.MaxSize)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/stretchr/testify/require"
+)
+
+const testSource = "git.example.com/team/policy"
+
+func testBundleFiles(version string) map[string][]byte {
+	return map[string][]byte{
+		constant.BundleFileName: []byte(fmt.Sprintf(`package {
+  name        = "policy"
+  repository  = "%s"
+  description = "%s"
+}
+`, testSource, version)),
+		"policy.rego": []byte("package policy\n\nallow := true\n"),
+		"data.json":   []byte(`{"key": "value"}`),
+	}
+}
+
+// newTestProxy serves the listed versions of the test bundle
+func newTestProxy(t *testing.T, versions ...string) *httptest.Server {
+	handler := http.NewServeMux()
+	handler.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		req, err := ParsePath(r.URL.Path)
+		if err != nil || req.Source != testSource {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch req.Kind {
+		case ListRequest:
+			for _, v := range versions {
+				fmt.Fprintln(w, v)
+			}
+		case InfoRequest:
+			json.NewEncoder(w).Encode(&VersionInfo{Version: req.Version, Time: time.Unix(0, 0), Hash: "0123456789ab"})
+		case ZipRequest:
+			var buf bytes.Buffer
+			require.NoError(t, archive.WriteZip(&buf, testBundleFiles(req.Version)))
+			w.Write(buf.Bytes())
+		}
+	})
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestClient(url string) *Client {
+	return &Client{
+		IO:      iostream.NewIOStream(iostream.WithOutput(io.Discard), iostream.WithErrOutput(io.Discard)),
+		URL:     url,
+		HTTP:    http.DefaultClient,
+		Encoder: &encode.Encoder{},
+	}
+}
+
+func mustParseVersion(t *testing.T, version string) *bundle.VersionSpec {
+	v, err := bundle.ParseVersionExpr(version)
+	require.NoError(t, err)
+	return v
+}
+
+type fakeDirect struct {
+	calls int
+}
+
+func (d *fakeDirect) Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error) {
+	d.calls++
+	return &bundle.Bundle{Source: source, Version: tag}, nil
+}
+
+func TestClientDownload(t *testing.T) {
+	server := newTestProxy(t, "v1.0.0", "v1.2.0", "v2.0.0")
+	client := newTestClient(server.URL)
+
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{"Exact version", "v1.0.0", "v1.0.0"},
+		{"Latest version", "", "v2.0.0"},
+		{"Constraint", "^1.0.0", "v1.2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := client.Download(context.Background(), testSource, mustParseVersion(t, tt.version))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, b.Version.String())
+			require.Equal(t, tt.want, b.BundleFile.Package.Description)
+			require.Equal(t, "0123456789ab", b.Version.Hash)
+			require.Contains(t, b.RegoFiles, "policy.rego")
+			require.Contains(t, b.OtherFiles, "data.json")
+		})
+	}
+
+	t.Run("Unknown source should be reported as not found", func(t *testing.T) {
+		_, err := client.Download(context.Background(), "git.example.com/team/other", mustParseVersion(t, "v1.0.0"))
+		require.ErrorIs(t, err, fetch.ErrNotFound)
+	})
+}
+
+func TestNewDownloader(t *testing.T) {
+	server := newTestProxy(t, "v1.0.0")
+
+	entries, err := ParseSetting(server.URL + ",direct")
+	require.NoError(t, err)
+
+	direct := new(fakeDirect)
+	downloader := NewDownloader(entries, direct, newTestClient)
+
+	_, err = downloader.Download(context.Background(), testSource, mustParseVersion(t, "v1.0.0"))
+	require.NoError(t, err)
+	require.Zero(t, direct.calls, "bundle available in proxy should not be downloaded directly")
+
+	_, err = downloader.Download(context.Background(), "git.example.com/team/other", mustParseVersion(t, "v1.0.0"))
+	require.NoError(t, err)
+	require.Equal(t, 1, direct.calls, "bundle not found in proxy should be downloaded directly")
+
+	entries, err = ParseSetting(server.URL + ",off")
+	require.NoError(t, err)
+
+	_, err = NewDownloader(entries, direct, newTestClient).Download(context.Background(), "git.example.com/team/other", nil)
+	require.ErrorContains(t, err, "disabled")
+}
+
+func TestParseSetting(t *testing.T) {
+	entries, err := ParseSetting("https://a.example.com|https://b.example.com,direct")
+	require.NoError(t, err)
+	require.Equal(t, []*Entry{
+		{URL: "https://a.example.com", AnyError: true},
+		{URL: "https://b.example.com"},
+		{URL: Direct},
+	}, entries)
+
+	_, err = ParseSetting("ftp://example.com")
+	require.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Bundle proxy protocol is similar to the Go module proxy protocol:
+//
+//	GET /{source}/@v/list             list of known versions, one per line
+//	GET /{source}/@v/{version}.info   JSON-encoded VersionInfo
+//	GET /{source}/@v/{version}.zip    zip archive with the bundle files
+const (
+	versionDir  = "/@v/"
+	listSuffix  = "list"
+	infoSuffix  = ".info"
+	zipSuffix   = ".zip"
+	contentJSON = "application/json"
+	contentZip  = "application/zip"
+	contentText = "text/plain; charset=utf-8"
+)
+
+// VersionInfo describes a single version of the bundle
+type VersionInfo struct {
+	Version string    // version string, e.g. 'v1.0.0'
+	Time    time.Time // commit time
+	Hash    string    // short commit hash, if known
+}
+
+type RequestKind int
+
+const (
+	ListRequest RequestKind = iota
+	InfoRequest
+	ZipRequest
+)
+
+// Request describes a parsed request of the protocol
+type Request struct {
+	Kind    RequestKind
+	Source  string
+	Version string // empty for list requests
+}
+
+func ListPath(source string) string {
+	return "/" + source + versionDir + listSuffix
+}
+
+func InfoPath(source, version string) string {
+	return "/" + source + versionDir + version + infoSuffix
+}
+
+func ZipPath(source, version string) string {
+	return "/" + source + versionDir + version + zipSuffix
+}
+
+// ParsePath parses the request path of the protocol
+func ParsePath(p string) (*Request, error) {
+	idx := strings.LastIndex(p, versionDir)
+	if idx <= 0 {
+		return nil, fmt.Errorf("invalid path '%s'", p)
+	}
+
+	source := strings.Trim(p[:idx], "/")
+	if source == "" || strings.Contains(source, "..") {
+		return nil, fmt.Errorf("invalid source in path '%s'", p)
+	}
+
+	rest := p[idx+len(versionDir):]
+	switch {
+	case rest == listSuffix:
+		return &Request{Kind: ListRequest, Source: source}, nil
+	case strings.HasSuffix(rest, infoSuffix) && len(rest) > len(infoSuffix):
+		return &Request{Kind: InfoRequest, Source: source, Version: strings.TrimSuffix(rest, infoSuffix)}, nil
+	case strings.HasSuffix(rest, zipSuffix) && len(rest) > len(zipSuffix):
+		return &Request{Kind: ZipRequest, Source: source, Version: strings.TrimSuffix(rest, zipSuffix)}, nil
+	default:
+		return nil, fmt.Errorf("invalid path '%s'", p)
+	}
+}
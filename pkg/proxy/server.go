@@ -0,0 +1,260 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil"
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/storage"
+)
+
+type serverStorage interface {
+	Some(repo string, version string) bool
+	Store(b *bundle.Bundle) error
+	Load(source string, version *bundle.VersionSpec) (*bundle.Bundle, error)
+	Versions(source string) ([]string, error)
+}
+
+type serverInspector interface {
+	Inspect(b *bundle.Bundle) error
+}
+
+type serverEncoder interface {
+	EncodeIgnoreFile(ignorefile *bundle.IgnoreFile) []byte
+	EncodeBundleFile(bundlefile *bundlefile.Schema) []byte
+	EncodeLockFile(lockfile *lockfile.Schema) []byte
+	DecodeBundleFile(content []byte) (*bundlefile.Schema, error)
+	DecodeIgnoreFile(content []byte) (*bundle.IgnoreFile, error)
+	DecodeLockFile(content []byte) (*lockfile.Schema, error)
+	Fileify(files map[string][]byte) (*encode.FileifyOutput, error)
+}
+
+// Server serves bundles of the storage using the proxy protocol. Bundles
+// can be uploaded with `PUT /{source}/@v/{version}.zip` either as zip or as
+// tar.gz archives produced by `bpm pack`, they are inspected before being
+// stored, and existing versions are never replaced.
+type Server struct {
+	IO        core.IO
+	Storage   serverStorage
+	Inspector serverInspector
+	Encoder   serverEncoder
+	Token     string // token required for uploads, uploads are disabled if empty
+
+	mu      sync.Mutex
+	uploads map[string]*sync.Mutex // locks of the uploaded versions
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := ParsePath(r.URL.Path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.serve(w, r, req)
+
+	case http.MethodPut:
+		if req.Kind != ZipRequest {
+			http.Error(w, "bundles can only be uploaded to the archive path", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.upload(w, r, req)
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, req *Request) {
+	if req.Kind == ListRequest {
+		versions, err := s.Storage.Versions(req.Source)
+		if err != nil {
+			s.fail(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentText)
+		for _, v := range versions {
+			fmt.Fprintln(w, v)
+		}
+
+		return
+	}
+
+	b, err := s.load(req.Source, req.Version)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotExist{}) {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.fail(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	switch req.Kind {
+	case InfoRequest:
+		w.Header().Set("Content-Type", contentJSON)
+		json.NewEncoder(w).Encode(&VersionInfo{
+			Version: b.Version.String(),
+			Time:    b.Version.Timestamp,
+			Hash:    b.Version.Hash,
+		})
+
+	case ZipRequest:
+		var buf bytes.Buffer
+		if err := archive.WriteZip(&buf, archive.BundleFiles(b, s.Encoder)); err != nil {
+			s.fail(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentZip)
+		w.Write(buf.Bytes())
+	}
+}
+
+func (s *Server) load(source, version string) (*bundle.Bundle, error) {
+	v, err := bundle.ParseVersionExpr(version)
+	if err != nil || v == nil || v.IsConstraint() {
+		return nil, storage.ErrNotExist{}
+	}
+
+	if !s.Storage.Some(source, v.String()) {
+		return nil, storage.ErrNotExist{}
+	}
+
+	return s.Storage.Load(source, v)
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, req *Request) {
+	if !s.authorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	v, err := bundle.ParseVersionExpr(req.Version)
+	if err != nil || v == nil || v.IsConstraint() || v.IsPseudo() {
+		http.Error(w, fmt.Sprintf("invalid version '%s', expected semantic version", req.Version), http.StatusBadRequest)
+		return
+	}
+
+	if s.Storage.Some(req.Source, v.String()) {
+		http.Error(w, fmt.Sprintf("bundle %s already exists", bundleutil.FormatSourceWithVersion(req.Source, v.String())), http.StatusConflict)
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r.Body, archive.MaxSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(content) > archive.MaxSize {
+		http.Error(w, "archive is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	b, err := s.decode(content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if b.Repository() != req.Source {
+		http.Error(w, fmt.Sprintf("bundle repository '%s' does not match '%s'", b.Repository(), req.Source), http.StatusBadRequest)
+		return
+	}
+
+	b.Source = req.Source
+	b.Version = v
+
+	if err := s.Inspector.Inspect(b); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// the version is checked again under the lock, since another
+	// upload of the same version could have been stored meanwhile
+	unlock := s.lockUpload(bundleutil.FormatSourceWithVersion(req.Source, v.String()))
+	defer unlock()
+
+	if s.Storage.Some(req.Source, v.String()) {
+		http.Error(w, fmt.Sprintf("bundle %s already exists", bundleutil.FormatSourceWithVersion(req.Source, v.String())), http.StatusConflict)
+		return
+	}
+
+	if err := s.Storage.Store(b); err != nil {
+		s.fail(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.IO.PrintfOk("bundle %s has been uploaded", bundleutil.FormatSourceWithVersion(req.Source, v.String()))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) lockUpload(key string) func() {
+	s.mu.Lock()
+	if s.uploads == nil {
+		s.uploads = make(map[string]*sync.Mutex)
+	}
+
+	l, exists := s.uploads[key]
+	if !exists {
+		l = new(sync.Mutex)
+		s.uploads[key] = l
+	}
+	s.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+func (s *Server) decode(content []byte) (*bundle.Bundle, error) {
+	files, err := archive.Read(content)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := archive.DecodeBundle(files, s.Encoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.BundleFile.Package == nil {
+		return nil, errors.New("package block is not defined")
+	}
+
+	return b, nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// fail reports the internal error without exposing its details to the client
+func (s *Server) fail(w http.ResponseWriter, err error, code int) {
+	s.IO.PrintfErr("%v", err)
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundleutil/archive"
+	"github.com/4rchr4y/bpm/bundleutil/encode"
+	"github.com/4rchr4y/bpm/bundleutil/inspect"
+	"github.com/4rchr4y/bpm/constant"
+	"github.com/4rchr4y/bpm/iostream"
+	"github.com/4rchr4y/bpm/storage"
+	"github.com/4rchr4y/godevkit/v3/syswrap"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+// packTestBundle returns a zip archive of a valid bundle
+// with the lock file checksum matching its contents
+func packTestBundle(t *testing.T, encoder *encode.Encoder) []byte {
+	files := testBundleFiles("v1.0.0")
+	delete(files, "policy.rego")
+	files["rules.rego"] = []byte("package policy.rules\n\nallow := true\n")
+
+	b, err := archive.DecodeBundle(files, encoder)
+	require.NoError(t, err)
+	b.LockFile.Sum = b.Sum()
+
+	var buf bytes.Buffer
+	require.NoError(t, archive.WriteZip(&buf, archive.BundleFiles(b, encoder)))
+
+	return buf.Bytes()
+}
+
+// slowStorage delays storing, so that concurrent uploads overlap
+type slowStorage struct {
+	*storage.Storage
+	delay time.Duration
+}
+
+func (s *slowStorage) Store(b *bundle.Bundle) error {
+	time.Sleep(s.delay)
+	return s.Storage.Store(b)
+}
+
+func newTestServer(t *testing.T, storeDelay time.Duration) (*httptest.Server, *encode.Encoder) {
+	io := iostream.NewIOStream(iostream.WithOutput(io.Discard), iostream.WithErrOutput(io.Discard))
+	encoder := &encode.Encoder{IO: io}
+
+	server := httptest.NewServer(&Server{
+		IO: io,
+		Storage: &slowStorage{
+			Storage: &storage.Storage{
+				Dir:     t.TempDir(),
+				IO:      io,
+				OSWrap:  new(syswrap.OSWrap),
+				IOWrap:  new(syswrap.IOWrap),
+				Encoder: encoder,
+			},
+			delay: storeDelay,
+		},
+		Inspector: &inspect.Inspector{IO: io},
+		Encoder:   encoder,
+		Token:     testToken,
+	})
+	t.Cleanup(server.Close)
+
+	return server, encoder
+}
+
+func upload(t *testing.T, url, token string, content []byte) int {
+	req, err := http.NewRequest(http.MethodPut, url+ZipPath(testSource, "v1.0.0"), bytes.NewReader(content))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestServer(t *testing.T) {
+	server, encoder := newTestServer(t, 0)
+	content := packTestBundle(t, encoder)
+
+	t.Run("Upload without valid token should be rejected", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, upload(t, server.URL, "invalid", content))
+	})
+
+	t.Run("Bundle with invalid checksum should be rejected", func(t *testing.T) {
+		files, err := archive.ReadZip(content)
+		require.NoError(t, err)
+		files["data.json"] = []byte(`{"key": "changed"}`)
+
+		var buf bytes.Buffer
+		require.NoError(t, archive.WriteZip(&buf, files))
+		require.Equal(t, http.StatusUnprocessableEntity, upload(t, server.URL, testToken, buf.Bytes()))
+	})
+
+	t.Run("Valid bundle should be accepted once", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, upload(t, server.URL, testToken, content))
+		require.Equal(t, http.StatusConflict, upload(t, server.URL, testToken, content))
+	})
+
+	t.Run("Concurrent uploads of the same version should be accepted once", func(t *testing.T) {
+		server, encoder := newTestServer(t, 50*time.Millisecond)
+		content := packTestBundle(t, encoder)
+
+		statuses := make(chan int, 8)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(statuses); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statuses <- upload(t, server.URL, testToken, content)
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		created := 0
+		for status := range statuses {
+			if status == http.StatusCreated {
+				created++
+				continue
+			}
+
+			require.Equal(t, http.StatusConflict, status)
+		}
+		require.Equal(t, 1, created)
+	})
+
+	t.Run("Uploaded bundle should be served", func(t *testing.T) {
+		client := newTestClient(server.URL)
+
+		list, err := client.List(context.Background(), testSource)
+		require.NoError(t, err)
+		require.Equal(t, []string{"v1.0.0"}, list)
+
+		b, err := client.Download(context.Background(), testSource, nil)
+		require.NoError(t, err)
+		require.Equal(t, "v1.0.0", b.Version.String())
+		require.Equal(t, b.LockFile.Sum, b.Sum())
+		require.Contains(t, b.OtherFiles, "data.json")
+		require.Contains(t, b.RegoFiles, "rules.rego")
+		require.NotContains(t, b.OtherFiles, constant.IgnoreFileName)
+	})
+
+	t.Run("Unknown version should not be found", func(t *testing.T) {
+		resp, err := http.Get(server.URL + InfoPath(testSource, "v2.0.0"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/fetch"
+	"github.com/4rchr4y/bpm/fetch/remote"
+)
+
+const (
+	Direct = "direct" // download bundles directly from their repositories
+	Off    = "off"    // disallow downloading bundles
+)
+
+// Entry is a single element of the proxy setting
+type Entry struct {
+	URL      string // proxy url, 'direct' or 'off'
+	AnyError bool   // fall back to the next entry on any error
+}
+
+// ParseSetting parses the proxy list in the same format as GOPROXY, e.g.
+// 'https://proxy.example.com,direct'. Entries separated by a comma fall back
+// to the next one only if the bundle is not found, while entries separated
+// by a pipe, e.g. 'https://proxy.example.com|direct', fall back on any error.
+func ParseSetting(str string) ([]*Entry, error) {
+	result := make([]*Entry, 0)
+
+	for str != "" {
+		var (
+			item     string
+			anyError bool
+		)
+
+		if idx := strings.IndexAny(str, ",|"); idx >= 0 {
+			item, anyError, str = str[:idx], str[idx] == '|', str[idx+1:]
+		} else {
+			item, str = str, ""
+		}
+
+		item = strings.TrimSpace(item)
+		switch item {
+		case "":
+			continue
+		case Direct, Off:
+		default:
+			u, err := url.Parse(item)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+				return nil, fmt.Errorf("invalid proxy '%s', expected url, '%s' or '%s'", remote.Redact(item), Direct, Off)
+			}
+		}
+
+		result = append(result, &Entry{URL: item, AnyError: anyError})
+	}
+
+	if len(result) == 0 {
+		result = append(result, &Entry{URL: Direct})
+	}
+
+	return result, nil
+}
+
+type downloader interface {
+	Download(ctx context.Context, source string, tag *bundle.VersionSpec) (*bundle.Bundle, error)
+}
+
+// NewDownloader builds the downloader that follows the order of the proxy
+// setting, `direct` is used for the 'direct' entries and `newClient` is
+// called to create the client of each proxy
+func NewDownloader(entries []*Entry, direct downloader, newClient func(url string) *Client) *fetch.FallbackDownloader {
+	result := &fetch.FallbackDownloader{
+		Steps: make([]*fetch.DownloadStep, 0, len(entries)),
+	}
+
+	for _, e := range entries {
+		step := &fetch.DownloadStep{Name: remote.Redact(e.URL), AnyError: e.AnyError}
+
+		switch e.URL {
+		case Direct:
+			step.Downloader = direct
+		case Off:
+			step.Downloader = fetch.DisabledDownloader{}
+		default:
+			step.Downloader = newClient(e.URL)
+		}
+
+		result.Steps = append(result.Steps, step)
+	}
+
+	return result
+}
@@ -0,0 +1,118 @@
+package resolver
+
+import (
+	"sort"
+	"strings"
+)
+
+// ConflictError is returned when no single version of
+// a source satisfies all of its requirements
+type ConflictError struct {
+	Conflicts []*Conflict
+}
+
+// Conflict describes all requirements of a single source
+// that cannot be satisfied by the selected version
+type Conflict struct {
+	Source   string
+	Selected *Node
+	Chains   [][]*Requirement // requirement chains from the root for each requirement of the source
+	Failed   []*Requirement   // requirements not satisfied by the selected version
+}
+
+func (e *ConflictError) Error() string {
+	var builder strings.Builder
+
+	for i, c := range e.Conflicts {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+
+		builder.WriteString("version conflict for ")
+		builder.WriteString(c.Source)
+		builder.WriteString(":")
+
+		for _, chain := range c.Chains {
+			builder.WriteString("\n\t> ")
+			builder.WriteString(formatChain(chain))
+
+			if c.isFailed(chain[len(chain)-1]) {
+				builder.WriteString(" (not satisfied by ")
+				builder.WriteString(c.Selected.Bundle.Version.String())
+				builder.WriteString(")")
+			}
+		}
+
+		builder.WriteString("\n\tno single version satisfies all requirements, highest required version is ")
+		builder.WriteString(c.Selected.String())
+	}
+
+	return builder.String()
+}
+
+func (c *Conflict) isFailed(edge *Requirement) bool {
+	for i := range c.Failed {
+		if c.Failed[i] == edge {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newConflictError(conflicts map[string][]*Requirement, selected map[string]*Node, reached map[*Node]*Requirement) *ConflictError {
+	sources := make([]string, 0, len(conflicts))
+	for source := range conflicts {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	result := &ConflictError{Conflicts: make([]*Conflict, 0, len(sources))}
+	for _, source := range sources {
+		c := &Conflict{
+			Source:   source,
+			Selected: selected[source],
+			Failed:   conflicts[source],
+		}
+
+		// collecting every requirement of the source that is reachable
+		// from the root, so that the whole picture is visible
+		for n := range reached {
+			for _, edge := range n.Require {
+				if edge.Source == source {
+					c.Chains = append(c.Chains, buildChain(edge, reached))
+				}
+			}
+		}
+
+		sort.Slice(c.Chains, func(i, j int) bool {
+			return formatChain(c.Chains[i]) < formatChain(c.Chains[j])
+		})
+
+		result.Conflicts = append(result.Conflicts, c)
+	}
+
+	return result
+}
+
+// buildChain restores the shortest path of requirements
+// from the root bundle to the specified requirement
+func buildChain(edge *Requirement, reached map[*Node]*Requirement) []*Requirement {
+	chain := []*Requirement{edge}
+	for parent := reached[edge.From]; parent != nil; parent = reached[parent.From] {
+		chain = append([]*Requirement{parent}, chain...)
+	}
+
+	return chain
+}
+
+func formatChain(chain []*Requirement) string {
+	parts := make([]string, 0, len(chain))
+	parts = append(parts, chain[0].From.Source)
+
+	for _, edge := range chain[:len(chain)-1] {
+		parts = append(parts, edge.Target.String())
+	}
+
+	return strings.Join(parts, " -> ") + " requires " + chain[len(chain)-1].String()
+}
@@ -0,0 +1,409 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/bundleutil"
+	"github.com/4rchr4y/bpm/core"
+	"github.com/4rchr4y/bpm/fetch"
+)
+
+type resolverFetcher interface {
+	FetchAll(ctx context.Context, requests []*fetch.FetchRequest) ([]*bundle.Bundle, error)
+}
+
+// Resolver builds the full requirement graph of a bundle and selects
+// exactly one version for each required source.
+//
+// The selection follows the minimal version selection (MVS) approach:
+// every requirement is resolved into a concrete version, and then for each
+// source the highest of all required versions is selected. After that, each
+// requirement reachable through the selected versions is checked against
+// the selected version, so that incompatible requirements (different major
+// versions or unsatisfied constraints) are reported as a conflict. Requirement
+// cycles among the selected versions are reported as an error as well.
+type Resolver struct {
+	IO      core.IO
+	Fetcher resolverFetcher
+}
+
+// Requirement is an edge of the requirement graph
+type Requirement struct {
+	From    *Node               // node that declares the requirement
+	Source  string              // required bundle source
+	Version *bundle.VersionSpec // version as declared, can be a constraint
+	Target  *Node               // node the requirement was resolved into
+}
+
+func (r *Requirement) String() string {
+	return bundleutil.FormatSourceWithVersion(r.Source, r.Version.String())
+}
+
+// Node is a single concrete version of a bundle in the requirement graph
+type Node struct {
+	Source     string
+	Bundle     *bundle.Bundle
+	Require    []*Requirement // outgoing requirements
+	RequiredBy []*Requirement // incoming requirements
+}
+
+func (n *Node) String() string {
+	if n.Bundle.Version == nil {
+		return n.Source
+	}
+
+	return bundleutil.FormatSourceWithVersion(n.Source, n.Bundle.Version.String())
+}
+
+type Resolution struct {
+	Root     *Node
+	Direct   []*bundle.Bundle // selected versions of requirements declared by the root bundle
+	Indirect []*bundle.Bundle // selected versions of all other requirements, sorted by source
+}
+
+// List returns all selected bundles, the direct ones first
+func (r *Resolution) List() []*bundle.Bundle {
+	result := make([]*bundle.Bundle, 0, len(r.Direct)+len(r.Indirect))
+	result = append(result, r.Direct...)
+	return append(result, r.Indirect...)
+}
+
+type graph struct {
+	root     *Node
+	nodes    map[string]*Node   // nodes by `source@version`, including constraint expressions
+	bySource map[string][]*Node // all reached versions of each source
+}
+
+func (r *Resolver) Resolve(ctx context.Context, root *bundle.Bundle) (*Resolution, error) {
+	g := &graph{
+		root:     &Node{Source: root.Repository(), Bundle: root},
+		nodes:    make(map[string]*Node),
+		bySource: make(map[string][]*Node),
+	}
+
+	// the graph is built level by level, so that all
+	// requirements of a level are fetched concurrently
+	level := []*Node{g.root}
+	for len(level) > 0 {
+		pending, err := g.collectPending(level)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := r.fetchPending(ctx, g, pending); err != nil {
+			return nil, err
+		}
+
+		next := make([]*Node, 0)
+		for _, p := range pending {
+			target, created := g.nodeOf(p)
+
+			edge := &Requirement{
+				From:    p.from,
+				Source:  p.source,
+				Version: p.declared,
+				Target:  target,
+			}
+
+			p.from.Require = append(p.from.Require, edge)
+			target.RequiredBy = append(target.RequiredBy, edge)
+
+			if created {
+				next = append(next, target)
+			}
+		}
+
+		level = next
+	}
+
+	return g.selectVersions()
+}
+
+type pendingRequirement struct {
+	from     *Node
+	source   string
+	declared *bundle.VersionSpec // version as declared
+	version  *bundle.VersionSpec // version that needs to be fetched
+	fetched  *bundle.Bundle
+}
+
+func (p *pendingRequirement) key() string {
+	return bundleutil.FormatSourceWithVersion(p.source, p.version.String())
+}
+
+// collectPending collects requirements of all nodes of the level
+func (g *graph) collectPending(level []*Node) ([]*pendingRequirement, error) {
+	result := make([]*pendingRequirement, 0)
+
+	for _, n := range level {
+		for _, decl := range n.Bundle.BundleFile.Require.List {
+			declared, err := bundle.ParseVersionExpr(decl.Version)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version of %s required by %s: %v", decl.Source, n.Source, err)
+			}
+
+			result = append(result, &pendingRequirement{
+				from:     n,
+				source:   decl.Source,
+				declared: declared,
+				version:  lockedVersion(n.Bundle.LockFile, decl.Source, declared),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// fetchPending fetches all requirements that have not been reached before
+func (r *Resolver) fetchPending(ctx context.Context, g *graph, pending []*pendingRequirement) error {
+	requests := make([]*fetch.FetchRequest, 0, len(pending))
+	targets := make(map[string][]*pendingRequirement, len(pending))
+
+	for _, p := range pending {
+		if p.source == g.root.Source {
+			continue
+		}
+
+		key := p.key()
+		if _, exists := g.nodes[key]; exists {
+			continue
+		}
+
+		if _, exists := targets[key]; !exists {
+			requests = append(requests, &fetch.FetchRequest{Source: p.source, Version: p.version})
+		}
+
+		targets[key] = append(targets[key], p)
+	}
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	bundles, err := r.Fetcher.FetchAll(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	for i, b := range bundles {
+		key := bundleutil.FormatSourceWithVersion(requests[i].Source, requests[i].Version.String())
+		for _, p := range targets[key] {
+			p.fetched = b
+		}
+	}
+
+	return nil
+}
+
+// nodeOf returns the node the requirement is resolved into,
+// creating it if this bundle version has not been reached before
+func (g *graph) nodeOf(p *pendingRequirement) (*Node, bool) {
+	if p.source == g.root.Source {
+		// the root bundle is required by one of its requirements,
+		// such an edge closes a cycle and is reported later
+		return g.root, false
+	}
+
+	exprKey := p.key()
+	if n, exists := g.nodes[exprKey]; exists {
+		return n, false
+	}
+
+	key := bundleutil.FormatSourceWithVersion(p.source, p.fetched.Version.String())
+	if n, exists := g.nodes[key]; exists {
+		g.nodes[exprKey] = n
+		return n, false
+	}
+
+	n := &Node{Source: p.source, Bundle: p.fetched}
+	g.nodes[key] = n
+	g.nodes[exprKey] = n
+	g.bySource[p.source] = append(g.bySource[p.source], n)
+
+	return n, true
+}
+
+func (g *graph) selectVersions() (*Resolution, error) {
+	selected := make(map[string]*Node, len(g.bySource)+1)
+	selected[g.root.Source] = g.root
+	for source, nodes := range g.bySource {
+		for _, n := range nodes {
+			if current, exists := selected[source]; !exists || isNewer(n.Bundle.Version, current.Bundle.Version) {
+				selected[source] = n
+			}
+		}
+	}
+
+	// walking only through the selected versions, since requirements
+	// of versions that were not selected do not affect the result
+	reached := map[*Node]*Requirement{g.root: nil} // node and the edge it was first reached by
+	queue := []*Node{g.root}
+	conflicts := make(map[string][]*Requirement)
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		for _, edge := range n.Require {
+			target := selected[edge.Source]
+			if !isSatisfied(edge.Version, target.Bundle.Version) {
+				conflicts[edge.Source] = append(conflicts[edge.Source], edge)
+			}
+
+			if _, exists := reached[target]; exists {
+				continue
+			}
+
+			reached[target] = edge
+			queue = append(queue, target)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, newConflictError(conflicts, selected, reached)
+	}
+
+	if cycle := findCycle(g.root, selected); cycle != nil {
+		return nil, &fetch.CycleError{Path: cycle}
+	}
+
+	result := &Resolution{Root: g.root}
+	direct := make(map[string]struct{}, len(g.root.Require))
+	for _, edge := range g.root.Require {
+		if _, exists := direct[edge.Source]; exists {
+			continue
+		}
+
+		direct[edge.Source] = struct{}{}
+		result.Direct = append(result.Direct, selected[edge.Source].Bundle)
+	}
+
+	indirect := make([]*Node, 0, len(reached))
+	for n := range reached {
+		if _, exists := direct[n.Source]; exists || n == g.root {
+			continue
+		}
+
+		indirect = append(indirect, n)
+	}
+
+	sort.Slice(indirect, func(i, j int) bool {
+		return indirect[i].Source < indirect[j].Source
+	})
+
+	for _, n := range indirect {
+		result.Indirect = append(result.Indirect, n.Bundle)
+	}
+
+	return result, nil
+}
+
+// findCycle looks for a cycle among the selected versions
+// and returns its path if there is one
+func findCycle(root *Node, selected map[string]*Node) []string {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	state := make(map[*Node]int)
+	stack := make([]*Node, 0)
+
+	var visit func(n *Node) []string
+	visit = func(n *Node) []string {
+		state[n] = visiting
+		stack = append(stack, n)
+
+		for _, edge := range n.Require {
+			target := selected[edge.Source]
+
+			switch state[target] {
+			case visiting:
+				cycle := make([]string, 0)
+				for i := len(stack) - 1; i >= 0; i-- {
+					cycle = append([]string{stack[i].String()}, cycle...)
+					if stack[i] == target {
+						break
+					}
+				}
+
+				return append(cycle, target.String())
+
+			case visited:
+				continue
+			}
+
+			if cycle := visit(target); cycle != nil {
+				return cycle
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[n] = visited
+		return nil
+	}
+
+	return visit(root)
+}
+
+// isNewer reports whether version a should be preferred over b
+func isNewer(a, b *bundle.VersionSpec) bool {
+	switch {
+	case a == nil:
+		// the version of a local bundle is unknown,
+		// so such bundle always takes precedence
+		return true
+	case b == nil:
+		return false
+	default:
+		return a.GreaterThan(b)
+	}
+}
+
+// isSatisfied reports whether the selected version can be used
+// instead of the required one
+func isSatisfied(required, selected *bundle.VersionSpec) bool {
+	if required == nil || selected == nil {
+		return true
+	}
+
+	if required.IsConstraint() {
+		return required.Match(selected)
+	}
+
+	if required.Equal(selected) {
+		return true
+	}
+
+	// a higher version is only compatible within the same major version
+	return selected.GreaterThan(required) && selected.Major() == required.Major()
+}
+
+// lockedVersion returns the concrete version from the lock
+// file if it satisfies the required constraint
+func lockedVersion(lockFile *lockfile.Schema, source string, required *bundle.VersionSpec) *bundle.VersionSpec {
+	if !required.IsConstraint() || lockFile == nil || lockFile.Require == nil {
+		return required
+	}
+
+	for _, req := range lockFile.Require.List {
+		if req.Source != source {
+			continue
+		}
+
+		v, err := bundle.ParseVersionExpr(req.Version)
+		if err != nil {
+			continue
+		}
+
+		if required.Match(v) {
+			return v
+		}
+	}
+
+	return required
+}
@@ -0,0 +1,237 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/4rchr4y/bpm/bundle"
+	"github.com/4rchr4y/bpm/bundle/bundlefile"
+	"github.com/4rchr4y/bpm/bundle/lockfile"
+	"github.com/4rchr4y/bpm/fetch"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFetcher struct {
+	bundles map[string][]*bundle.Bundle
+	calls   map[string]int
+}
+
+func (f *fakeFetcher) add(source, version string, requires ...string) {
+	v, err := bundle.ParseVersionExpr(version)
+	if err != nil {
+		panic(err)
+	}
+
+	f.bundles[source] = append(f.bundles[source], newTestBundle(source, v, requires...))
+}
+
+func (f *fakeFetcher) FetchAll(ctx context.Context, requests []*fetch.FetchRequest) ([]*bundle.Bundle, error) {
+	result := make([]*bundle.Bundle, len(requests))
+	for i, r := range requests {
+		b, err := f.fetch(r.Source, r.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = b
+	}
+
+	return result, nil
+}
+
+func (f *fakeFetcher) fetch(source string, v *bundle.VersionSpec) (*bundle.Bundle, error) {
+	f.calls[source+"@"+v.String()]++
+
+	var result *bundle.Bundle
+	for _, b := range f.bundles[source] {
+		if v != nil && !v.Match(b.Version) {
+			continue
+		}
+
+		if result == nil || b.Version.GreaterThan(result.Version) {
+			result = b
+		}
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("version '%s' is not found", v.String())
+	}
+
+	return result, nil
+}
+
+// newTestBundle creates a bundle with requirements in the form of 'source version'
+func newTestBundle(source string, v *bundle.VersionSpec, requires ...string) *bundle.Bundle {
+	requireList := make([]*bundlefile.RequirementDecl, 0, len(requires))
+	for _, r := range requires {
+		s, version, _ := strings.Cut(r, " ")
+
+		requireList = append(requireList, &bundlefile.RequirementDecl{
+			Source:  s,
+			Name:    path.Base(s),
+			Version: version,
+		})
+	}
+
+	return &bundle.Bundle{
+		Source:  source,
+		Version: v,
+		BundleFile: &bundlefile.Schema{
+			Package: &bundlefile.PackageBlock{Name: path.Base(source), Repository: source},
+			Require: &bundlefile.RequireBlock{List: requireList},
+		},
+		LockFile: lockfile.PrepareSchema(nil),
+	}
+}
+
+func newTestResolver() (*Resolver, *fakeFetcher) {
+	fetcher := &fakeFetcher{
+		bundles: make(map[string][]*bundle.Bundle),
+		calls:   make(map[string]int),
+	}
+
+	return &Resolver{Fetcher: fetcher}, fetcher
+}
+
+func versions(list []*bundle.Bundle) []string {
+	result := make([]string, len(list))
+	for i, b := range list {
+		result[i] = b.Source + "@" + b.Version.String()
+	}
+
+	return result
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("The highest required version should be selected for each source", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "c v1.1.0")
+		fetcher.add("b", "v1.0.0", "c v1.3.0")
+		fetcher.add("c", "v1.1.0")
+		fetcher.add("c", "v1.3.0")
+		fetcher.add("c", "v1.4.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0", "b v1.0.0")
+		result, err := r.Resolve(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a@v1.0.0", "b@v1.0.0"}, versions(result.Direct))
+		require.Equal(t, []string{"c@v1.3.0"}, versions(result.Indirect))
+	})
+
+	t.Run("Requirements of versions that were not selected should be ignored", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "c v1.0.0")
+		fetcher.add("a", "v1.1.0")
+		fetcher.add("b", "v1.0.0", "a v1.1.0")
+		fetcher.add("c", "v1.0.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0", "b v1.0.0")
+		result, err := r.Resolve(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a@v1.1.0", "b@v1.0.0"}, versions(result.Direct))
+		require.Empty(t, result.Indirect)
+	})
+
+	t.Run("Each bundle version should be fetched only once", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "c v1.0.0")
+		fetcher.add("b", "v1.0.0", "c v1.0.0")
+		fetcher.add("c", "v1.0.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0", "b v1.0.0")
+		_, err := r.Resolve(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, 1, fetcher.calls["c@v1.0.0"])
+	})
+
+	t.Run("Constraint should be resolved into the highest matching version", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.2.0")
+		fetcher.add("a", "v1.5.0")
+		fetcher.add("a", "v2.0.0")
+
+		root := newTestBundle("root", nil, "a ~> 1.2")
+		result, err := r.Resolve(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a@v1.5.0"}, versions(result.Direct))
+	})
+
+	t.Run("Constraint satisfied by the locked version should not be resolved again", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.2.0")
+		fetcher.add("a", "v1.5.0")
+
+		root := newTestBundle("root", nil, "a ~> 1.2")
+		root.LockFile.Require.List = append(root.LockFile.Require.List, &lockfile.RequirementDecl{
+			Source:    "a",
+			Direction: lockfile.Direct.String(),
+			Version:   "v1.2.0",
+		})
+
+		result, err := r.Resolve(context.Background(), root)
+		require.NoError(t, err)
+		require.Equal(t, []string{"a@v1.2.0"}, versions(result.Direct))
+	})
+
+	t.Run("Different major versions should be reported as a conflict", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "c v1.0.0")
+		fetcher.add("b", "v1.0.0", "c v2.0.0")
+		fetcher.add("c", "v1.0.0")
+		fetcher.add("c", "v2.0.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0", "b v1.0.0")
+		_, err := r.Resolve(context.Background(), root)
+		require.Error(t, err)
+
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Len(t, conflictErr.Conflicts, 1)
+		require.Equal(t, "c", conflictErr.Conflicts[0].Source)
+		require.Contains(t, err.Error(), "root -> a@v1.0.0 requires c@v1.0.0 (not satisfied by v2.0.0)")
+		require.Contains(t, err.Error(), "root -> b@v1.0.0 requires c@v2.0.0")
+	})
+
+	t.Run("Unsatisfied constraint should be reported as a conflict", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "c < 1.2")
+		fetcher.add("b", "v1.0.0", "c v1.2.0")
+		fetcher.add("c", "v1.1.0")
+		fetcher.add("c", "v1.2.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0", "b v1.0.0")
+		_, err := r.Resolve(context.Background(), root)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "root -> a@v1.0.0 requires c@< 1.2 (not satisfied by v1.2.0)")
+	})
+
+	t.Run("Requirement cycle should be reported with the full path", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "b v2.0.0")
+		fetcher.add("b", "v2.0.0", "a v1.0.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0")
+		_, err := r.Resolve(context.Background(), root)
+		require.Error(t, err)
+
+		var cycleErr *fetch.CycleError
+		require.ErrorAs(t, err, &cycleErr)
+		require.Equal(t, []string{"a@v1.0.0", "b@v2.0.0", "a@v1.0.0"}, cycleErr.Path)
+	})
+
+	t.Run("Requirement of the root bundle should be reported as a cycle", func(t *testing.T) {
+		r, fetcher := newTestResolver()
+		fetcher.add("a", "v1.0.0", "root v1.0.0")
+
+		root := newTestBundle("root", nil, "a v1.0.0")
+		_, err := r.Resolve(context.Background(), root)
+		require.Error(t, err)
+
+		var cycleErr *fetch.CycleError
+		require.ErrorAs(t, err, &cycleErr)
+		require.Equal(t, []string{"root", "a@v1.0.0", "root"}, cycleErr.Path)
+	})
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Versions returns all versions of the source that are available in the
+// storage. Semantic versions go first in ascending order, the versions that
+// cannot be parsed follow them in lexical order.
+func (s *Storage) Versions(source string) ([]string, error) {
+	prefix := filepath.Base(s.MakeBundleSourcePath(source, ""))
+	dir := filepath.Dir(s.MakeBundleSourcePath(source, ""))
+
+	exists, err := s.OSWrap.Exists(dir)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	err = s.OSWrap.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == dir || !info.IsDir() {
+			return nil
+		}
+
+		if version := strings.TrimPrefix(info.Name(), prefix); version != info.Name() && version != "" {
+			result = append(result, version)
+		}
+
+		// only the entries of the directory itself are needed
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory '%s': %v", dir, err)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		vi, erri := version.NewSemver(result[i])
+		vj, errj := version.NewSemver(result[j])
+
+		switch {
+		case erri == nil && errj == nil:
+			return vi.LessThan(vj)
+		case erri == nil || errj == nil:
+			return erri == nil
+		default:
+			return result[i] < result[j]
+		}
+	})
+
+	return result, nil
+}
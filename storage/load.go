@@ -98,6 +98,22 @@ func (s *Storage) LoadFromAbs(path string, v *bundle.VersionSpec) (*bundle.Bundl
 	}, nil
 }
 
+// LoadManifests reads only the bundle file and the lock file of the
+// bundle located at the path, without loading and parsing its files
+func (s *Storage) LoadManifests(path string) (*bundlefile.Schema, *lockfile.Schema, error) {
+	bundleFile, err := s.readBundleFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lockFile, err := s.readLockFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bundlefile.PrepareSchema(bundleFile), lockfile.PrepareSchema(lockFile), nil
+}
+
 func (fetcher *Storage) readBundleDir(abs string, ignoreFile *bundle.IgnoreFile) (map[string][]byte, error) {
 	files := make(map[string][]byte)
 	err := fetcher.OSWrap.Walk(abs, func(path string, info os.FileInfo, err error) error {
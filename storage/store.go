@@ -30,6 +30,12 @@ func (s *Storage) Store(b *bundle.Bundle) error {
 		}
 	}
 
+	for path, content := range b.OtherFiles {
+		if err := s.processOtherFile(path, content, dirPath); err != nil {
+			return err
+		}
+	}
+
 	if err := s.processLockFile(b.LockFile, dirPath); err != nil {
 		return fmt.Errorf("failed to encode %s file: %v", b.LockFile.Filename(), err)
 	}
@@ -67,7 +73,11 @@ func (s *Storage) processBundleFile(bundleFile *bundlefile.Schema, dir string) e
 }
 
 func (s *Storage) processRegoFile(file *regofile.File, dir string) error {
-	pathToSave := filepath.Join(dir, file.Path)
+	return s.processOtherFile(file.Path, file.Raw, dir)
+}
+
+func (s *Storage) processOtherFile(path string, content []byte, dir string) error {
+	pathToSave := filepath.Join(dir, path)
 	dirToSave := filepath.Dir(pathToSave)
 
 	if _, err := os.Stat(dirToSave); os.IsNotExist(err) {
@@ -78,7 +88,7 @@ func (s *Storage) processRegoFile(file *regofile.File, dir string) error {
 		return fmt.Errorf("error checking directory '%s': %v", dirToSave, err)
 	}
 
-	if err := s.OSWrap.WriteFile(pathToSave, file.Raw, 0644); err != nil {
+	if err := s.OSWrap.WriteFile(pathToSave, content, 0644); err != nil {
 		return fmt.Errorf("failed to write file '%s': %v", pathToSave, err)
 	}
 